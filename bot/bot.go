@@ -0,0 +1,170 @@
+// Package bot plays hide and seek. A Bot is told whatever a player would be
+// told (the messages in hideandseek) and, every so often, asked for a move.
+// It plays by the same rules as everyone else: its moves are hideandseek
+// Commands, checked by the Game like anyone's.
+//
+// How a bot picks its moves is up to its Strategy (see strategy.go),
+// which depends on its Difficulty and whether it's seeking or hiding.
+package bot
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/edmangimelli/hide-and-seek/hideandseek"
+)
+
+type Bot struct {
+	Name string
+	Difficulty Difficulty
+	emoji string
+	random *rand.Rand
+
+	// round variables
+	forest hideandseek.Forest
+	rules hideandseek.Ruleset
+	seeker string // seeker's emoji
+	here Cell
+	others map[string]Cell // emoji -> where they are (seekers only see who's InSight)
+	visits [][]int // when we were last at each cell (see View)
+	moves int
+	found bool
+	going bool
+}
+
+func New(name string, d Difficulty, seed int64) *Bot {
+	return &Bot{
+		Name: name,
+		Difficulty: d,
+		random: rand.New(rand.NewSource(seed)),
+		others: make(map[string]Cell),
+	}
+}
+
+func (b *Bot) amSeeker() bool {
+	return b.seeker == b.emoji
+}
+
+// Tell the bot something the game said. It returns anything
+// the bot wants to say back right away (it's polite about being ready).
+func (b *Bot) Tell(m interface{}) []hideandseek.Command {
+	switch m := m.(type) {
+	case hideandseek.Initialized:
+		b.emoji = m.Emoji
+
+	case hideandseek.Wait:
+		b.emoji = m.Emoji
+
+	case hideandseek.Setup:
+		b.forest = m.Forest
+		b.rules = m.Rules
+		b.seeker = m.Seeker
+		b.found = false
+		b.going = false
+		b.others = make(map[string]Cell)
+		b.moves = 0
+		b.visits = make([][]int, len(m.Forest))
+		for r := range b.visits {
+			b.visits[r] = make([]int, len(m.Forest[r]))
+		}
+		for _, p := range m.Players {
+			if p.Emoji == b.emoji {
+				b.arrive(Cell{p.Row, p.Col})
+			} else if !b.amSeeker() {
+				b.others[p.Emoji] = Cell{p.Row, p.Col}
+			}
+		}
+		return []hideandseek.Command{hideandseek.Ready{Name: b.Name, Stage: hideandseek.ReadyToGo}}
+
+	case hideandseek.Go:
+		b.going = true
+
+	case hideandseek.Moved:
+		if m.Emoji == b.emoji {
+			b.arrive(Cell{m.ToRow, m.ToCol})
+		} else if !b.amSeeker() {
+			b.others[m.Emoji] = Cell{m.ToRow, m.ToCol}
+		}
+
+	case hideandseek.Found:
+		if m.Emoji == b.emoji {
+			b.found = true
+		}
+		delete(b.others, m.Emoji)
+
+	case hideandseek.Left:
+		delete(b.others, m.Emoji)
+
+	case hideandseek.InSight:
+		b.others = make(map[string]Cell)
+		for _, p := range m.Players {
+			b.others[p.Emoji] = Cell{p.Row, p.Col}
+		}
+
+	case hideandseek.TreeRemoved:
+		if b.forest != nil {
+			b.forest[m.Row][m.Col] = ' '
+		}
+
+	case hideandseek.Winner:
+		b.going = false
+		return []hideandseek.Command{hideandseek.Ready{Name: b.Name, Stage: hideandseek.ReadyForNextSetup}}
+
+	case hideandseek.RoundOver:
+		b.going = false
+		if !m.CantContinue {
+			return []hideandseek.Command{hideandseek.Ready{Name: b.Name, Stage: hideandseek.ReadyForNextSetup}}
+		}
+	}
+	return nil
+}
+
+func (b *Bot) arrive(c Cell) {
+	b.here = c
+	b.moves++
+	if c.Row >= 0 && c.Row < len(b.visits) && c.Col >= 0 && c.Col < len(b.visits[c.Row]) {
+		b.visits[c.Row][c.Col] = b.moves
+	}
+}
+
+// Delay is how long to wait before asking for the next move.
+func (b *Bot) Delay() time.Duration {
+	if b.amSeeker() {
+		return 850 * time.Millisecond
+	}
+	return time.Duration(1000 + b.random.Intn(2000)) * time.Millisecond
+}
+
+// Move returns the bot's next move, if it has one.
+func (b *Bot) Move() (hideandseek.Command, bool) {
+	if !b.going || b.found {
+		return nil, false
+	}
+
+	v := b.view()
+	to, ok := b.Difficulty.strategy(v.Seeker).Next(v, b.random)
+	if !ok || to == b.here || !v.CanMoveTo(to) {
+		return nil, false
+	}
+	return hideandseek.Move{Name: b.Name, Row: to.Row, Col: to.Col}, true
+}
+
+func (b *Bot) view() View {
+	v := View{
+		Forest: b.forest,
+		Rules: b.rules,
+		Seeker: b.amSeeker(),
+		Here: b.here,
+		Visits: b.visits,
+		Moves: b.moves,
+	}
+	for emoji, c := range b.others {
+		if emoji == b.seeker {
+			seeker := c
+			v.SeekerAt = &seeker
+		} else {
+			v.Hiders = append(v.Hiders, c)
+		}
+	}
+	return v
+}
@@ -0,0 +1,318 @@
+package bot
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/edmangimelli/hide-and-seek/hideandseek"
+)
+
+type Cell struct {
+	Row, Col int
+}
+
+// View is what a bot knows when it's asked for a move.
+type View struct {
+	Forest hideandseek.Forest
+	Rules hideandseek.Ruleset // filled in, as sent with setup
+	Seeker bool // are we the seeker
+	Here Cell
+
+	// hiders can see everyone; seekers only see the hiders in sight (see Ruleset.Sight)
+	SeekerAt *Cell
+	Hiders []Cell
+
+	// Visits[row][col] is the move number we were last there on (0 = never).
+	// Moves is the current move number, so Moves - Visits is how stale a cell is.
+	Visits [][]int
+	Moves int
+}
+
+// A Strategy picks the next move. ok = false means stay put.
+type Strategy interface {
+	Next(v View, random *rand.Rand) (to Cell, ok bool)
+}
+
+func (v View) inForest(c Cell) bool {
+	return c.Row >= 0 && c.Row < len(v.Forest) && c.Col >= 0 && c.Col < len(v.Forest[c.Row])
+}
+
+func (v View) tree(c Cell) bool {
+	return v.inForest(c) && hideandseek.Hideable(v.Forest[c.Row][c.Col])
+}
+
+// CanMoveTo follows the server's rules, as far as we can see them.
+func (v View) CanMoveTo(c Cell) bool {
+	if !v.inForest(c) || c == v.Here || !v.inReach(v.Here, c) {
+		return false
+	}
+	if !v.passable(v.Here, c) {
+		return false
+	}
+	if v.Seeker {
+		return true
+	}
+	if !v.tree(c) || (v.SeekerAt != nil && *v.SeekerAt == c) {
+		return false
+	}
+	for _, h := range v.Hiders {
+		if h == c { return false }
+	}
+	return true
+}
+
+// passable is whether no walls are in the way of a move from one cell to another
+func (v View) passable(from, to Cell) bool {
+	return v.Rules.Filled().CanReach(v.Forest, from.Row, from.Col, to.Row, to.Col)
+}
+
+// steps is how many moves it takes to get from c to every cell in the
+// forest, going around walls (-1 if you can't get there at all)
+func (v View) steps(c Cell) [][]int {
+	steps := make([][]int, len(v.Forest))
+	for r := range steps {
+		steps[r] = make([]int, len(v.Forest[r]))
+		for col := range steps[r] {
+			steps[r][col] = -1
+		}
+	}
+	steps[c.Row][c.Col] = 0
+	queue := []Cell{c}
+	for len(queue) > 0 {
+		here := queue[0]
+		queue = queue[1:]
+		for _, n := range v.reach(here) {
+			if !v.inForest(n) || steps[n.Row][n.Col] >= 0 || !v.passable(here, n) {
+				continue
+			}
+			steps[n.Row][n.Col] = steps[here.Row][here.Col] + 1
+			queue = append(queue, n)
+		}
+	}
+	return steps
+}
+
+// pounce is a hider the seeker can see and get to this move, if there is one
+func (v View) pounce() (Cell, bool) {
+	if !v.Seeker { return Cell{}, false }
+	for _, h := range v.Hiders {
+		if v.CanMoveTo(h) { return h, true }
+	}
+	return Cell{}, false
+}
+
+// Options lists everywhere we could go from here.
+func (v View) Options() []Cell {
+	var options []Cell
+	for _, c := range v.reach(v.Here) {
+		if v.CanMoveTo(c) {
+			options = append(options, c)
+		}
+	}
+	return options
+}
+
+func (v View) radius() int {
+	if v.Rules.MoveRadius < 1 {
+		return 1
+	}
+	return v.Rules.MoveRadius
+}
+
+func (v View) inReach(from, to Cell) bool {
+	dr, dc := abs(to.Row-from.Row), abs(to.Col-from.Col)
+	if v.Rules.NoDiagonals {
+		return dr+dc <= v.radius()
+	}
+	return dr <= v.radius() && dc <= v.radius()
+}
+
+// reach is every cell one move from c could get to, in the forest or not
+func (v View) reach(c Cell) []Cell {
+	var cells []Cell
+	radius := v.radius()
+	for r := c.Row-radius; r <= c.Row+radius; r++ {
+		for col := c.Col-radius; col <= c.Col+radius; col++ {
+			n := Cell{r, col}
+			if n != c && v.inReach(c, n) {
+				cells = append(cells, n)
+			}
+		}
+	}
+	return cells
+}
+
+func distance(a, b Cell) int { // in steps (diagonals count as one)
+	dr, dc := abs(a.Row-b.Row), abs(a.Col-b.Col)
+	if dr > dc { return dr }
+	return dc
+}
+
+func abs(n int) int {
+	if n < 0 { return -n }
+	return n
+}
+
+// RandomWalker wanders. It's what the JavaScript bot in client.html does.
+type RandomWalker struct{}
+
+func (RandomWalker) Next(v View, random *rand.Rand) (Cell, bool) {
+	options := v.Options()
+	if len(options) == 0 {
+		return Cell{}, false
+	}
+	return options[random.Intn(len(options))], true
+}
+
+// Sweeper (seekers) heads for the closest tree it hasn't been to,
+// and once it's been everywhere, the one it's been away from longest.
+type Sweeper struct{}
+
+func (Sweeper) Next(v View, random *rand.Rand) (Cell, bool) {
+	if !v.Seeker {
+		return RandomWalker{}.Next(v, random)
+	}
+	if h, ok := v.pounce(); ok {
+		return h, true
+	}
+
+	// breadth first from here; seekers can walk anywhere in the forest
+	first := map[Cell]Cell{} // cell -> first step on the way there
+	queue := []Cell{}
+	for _, o := range v.Options() {
+		first[o] = o
+		queue = append(queue, o)
+	}
+
+	var target Cell
+	found := false
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if v.tree(c) && (!found || v.Visits[c.Row][c.Col] < v.Visits[target.Row][target.Col]) {
+			target, found = c, true
+		}
+		for _, n := range v.reach(c) {
+			if _, seen := first[n]; seen || n == v.Here || !v.inForest(n) || !v.passable(c, n) {
+				continue
+			}
+			first[n] = first[c]
+			queue = append(queue, n)
+		}
+	}
+
+	if !found {
+		return RandomWalker{}.Next(v, random)
+	}
+	return first[target], true
+}
+
+// Evasive (hiders) keeps as far from the seeker as it can.
+type Evasive struct{}
+
+func (Evasive) Next(v View, random *rand.Rand) (Cell, bool) {
+	if v.Seeker || v.SeekerAt == nil {
+		return RandomWalker{}.Next(v, random)
+	}
+
+	best := []Cell{v.Here} // staying put is an option
+	farthest := distance(v.Here, *v.SeekerAt)
+	for _, o := range v.Options() {
+		d := distance(o, *v.SeekerAt)
+		switch {
+		case d > farthest:
+			best, farthest = []Cell{o}, d
+		case d == farthest:
+			best = append(best, o)
+		}
+	}
+
+	to := best[random.Intn(len(best))]
+	return to, to != v.Here
+}
+
+// Searcher (seekers) goes where a hider is most likely to be. Hiders move,
+// so a tree gets likelier the longer it's been since we looked, and nearby
+// trees count for more than far away ones.
+type Searcher struct{}
+
+func (Searcher) Next(v View, random *rand.Rand) (Cell, bool) {
+	if !v.Seeker {
+		return Evasive{}.Next(v, random)
+	}
+	if h, ok := v.pounce(); ok {
+		return h, true
+	}
+
+	options := v.Options()
+	if len(options) == 0 {
+		return Cell{}, false
+	}
+
+	var best Cell
+	bestScore := -1.0
+	for _, o := range options {
+		score := random.Float64() * 0.01 // break ties
+		steps := v.steps(o)
+		for r := range v.Forest {
+			for c := range v.Forest[r] {
+				t := Cell{r, c}
+				if !v.tree(t) || steps[r][c] < 0 { continue }
+				stale := float64(v.Moves - v.Visits[r][c])
+				if v.Visits[r][c] == 0 {
+					stale *= 2 // never been there at all
+				}
+				d := float64(1 + steps[r][c])
+				score += stale / (d * d)
+			}
+		}
+		if score > bestScore {
+			best, bestScore = o, score
+		}
+	}
+	return best, true
+}
+
+type Difficulty int
+
+const (
+	Easy Difficulty = iota
+	Medium
+	Hard
+)
+
+var ErrUnknownDifficulty = errors.New("difficulty must be easy, medium or hard")
+
+func ParseDifficulty(s string) (Difficulty, error) {
+	switch s {
+	case "easy":
+		return Easy, nil
+	case "", "medium":
+		return Medium, nil
+	case "hard":
+		return Hard, nil
+	}
+	return Medium, ErrUnknownDifficulty
+}
+
+func (d Difficulty) String() string {
+	switch d {
+	case Easy:
+		return "easy"
+	case Hard:
+		return "hard"
+	}
+	return "medium"
+}
+
+func (d Difficulty) strategy(seeker bool) Strategy {
+	switch {
+	case d == Easy:
+		return RandomWalker{}
+	case d == Medium && seeker:
+		return Sweeper{}
+	case d == Hard && seeker:
+		return Searcher{}
+	}
+	return Evasive{}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/edmangimelli/hide-and-seek/bot"
+	"github.com/edmangimelli/hide-and-seek/hideandseek"
+	"github.com/edmangimelli/hide-and-seek/protocol"
+)
+
+var autoBots = flag.Bool("autobots", true, "add a bot for anyone left without hiders")
+var autoBotLevel = flag.String("autobotlevel", "medium", "how good those bots are: easy, medium or hard")
+
+var botSeeds = rand.New(rand.NewSource(time.Now().UnixNano()))
+var botSeedsMutex = sync.Mutex{} // games add bots from their own goroutines
+
+func botSeed() int64 {
+	botSeedsMutex.Lock()
+	defer botSeedsMutex.Unlock()
+	return botSeeds.Int63()
+}
+
+// addBot sits a new bot down in the game (GAME GOROUTINE)
+// Bots are connected like everyone else; they just speak JSON
+// over a channel instead of a websocket.
+func (g *game) addBot(d bot.Difficulty) {
+	var name string
+	for i := 1; ; i++ {
+		name = "Bot"
+		if i > 1 {
+			name = fmt.Sprintf("Bot %d", i)
+		}
+		if !g.engine.Has(name) && g.conns[name] == nil {
+			break
+		}
+	}
+
+	if g.engine.Full() { // bots don't wait in line
+		log.Printf("\n%s: couldn't add a bot: %s\n", g.engine.Code(), hideandseek.ErrGameFull)
+		return
+	}
+
+	b := bot.New(name, d, botSeed())
+	c := newConnection(protocol.JSON)
+	c.setWho(g.engine.Code(), name)
+	events, err := g.engine.Handle(hideandseek.Join{Name: name, Bot: true})
+	if err != nil {
+		log.Printf("\n%s: couldn't add a bot: %s\n", g.engine.Code(), err)
+		return
+	}
+	log.Printf("\n%s: %s bot added: %s\n", g.engine.Code(), d, name)
+	g.conns[name] = c
+	g.bots[name] = true
+	go runBot(g, b, c)
+	g.deliver(events)
+}
+
+func runBot(g *game, b *bot.Bot, c *connection) {
+	defer close(c.closed)
+	defer func() { // in case we were hung up on, rather than told to go
+		go g.do(func() { g.disconnect(b.Name, c) })
+	}()
+
+	cmds := make(chan hideandseek.Command, 16)
+	defer close(cmds)
+	go func() {
+		for cmd := range cmds {
+			g.do(func() {
+				if g.conns[b.Name] == c {
+					events, _ := g.engine.Handle(cmd) // a rejected move just means the bot stays put
+					g.deliver(events)
+				}
+			})
+		}
+	}()
+
+	timer := time.NewTimer(b.Delay())
+	defer timer.Stop()
+	for {
+		select {
+		case rawMsg := <-c.out:
+			if rawMsg == "close" {
+				return
+			}
+			msg, err := c.codec.Decode([]byte(rawMsg))
+			if err != nil {
+				continue
+			}
+			for _, cmd := range b.Tell(msg) {
+				cmds <- cmd
+			}
+		case <-c.kicked:
+			return
+		case <-timer.C:
+			if cmd, ok := b.Move(); ok {
+				select {
+				case cmds <- cmd:
+				default: // still busy with the last one
+				}
+			}
+			timer.Reset(b.Delay())
+		}
+	}
+}
+
+// botsLeaveIfAlone takes the bots out of a game nobody's playing in anymore (GAME GOROUTINE)
+func (g *game) botsLeaveIfAlone() {
+	if len(g.bots) == 0 {
+		return
+	}
+	for _, n := range g.engine.Players() {
+		if !g.bots[n] {
+			return
+		}
+	}
+
+	var leaving []*connection
+	for n := range g.bots {
+		leaving = append(leaving, g.conns[n])
+		delete(g.conns, n)
+		delete(g.bots, n)
+		events, _ := g.engine.Handle(hideandseek.Leave{Name: n})
+		g.deliver(events)
+	}
+	for _, c := range leaving {
+		c.send("close")
+	}
+}
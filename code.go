@@ -1,34 +1,340 @@
 package main
 
 import (
+	"bufio"
 	"errors"
-	//"fmt"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
 )
 
-const letters = "ACEFHJKMNPRTWXY"
-const digits = "23456789"
-const either = letters + digits
-const maxCodes = len(letters)*len(either)*len(digits)*len(digits)
-var codesProduced = 0
+var codePattern = flag.String("codepattern", "LEDD", "game code pattern: L = letter, D = digit, E = either")
+var codeLetters = flag.String("codeletters", "ACEFHJKMNPRTWXY", "letters game codes can use")
+var codeDigits = flag.String("codedigits", "23456789", "digits game codes can use")
+var codeCheck = flag.Bool("codecheck", true, "end game codes with a check character, to catch typos")
+var codeCrowded = flag.Float64("codecrowded", 0.5, "once this much of the codes are in use, new ones get a digit longer")
+var codeBlocklist = flag.String("codeblocklist", "", "file of words (one per line) game codes mustn't spell, on top of the built in ones")
+var codeQuarantine = flag.Duration("codequarantine", 10*time.Minute, "how long a finished game's code sits out before it's used again")
 
+const maxExtraDigits = 3 // how much longer than -codepattern codes can get
+
+var ErrNoCodes = errors.New("All possible game codes are in use!")
+var ErrCodeInUse = errors.New("couldn't make a game code; try again")
+
+// blocklist is what codes mustn't spell. Codes are checked with digits
+// read as the letters they look like (see readAsLetters).
+var blocklist = []string{
+	"ASS", "CUM", "FAG", "FUK", "FUC", "FCK", "SEX", "TIT", "XXX", "KKK", "WTF",
+	"NAZI", "CRAP", "DICK", "COCK", "CUNT", "SHIT", "PISS", "FUCK", "SLUT", "WHORE",
+	"HATE", "KILL", "DIE", "DEAD", "POO", "PEE",
+}
+var readAsLetters = strings.NewReplacer("0", "O", "1", "I", "3", "E", "4", "A", "5", "S", "7", "T", "8", "B")
+
+// A codeSpace is every code of one length. Every code in it has a number
+// (see codeFor), and it hands out numbers nobody's using in constant time:
+// numbers [0, remaining) are the free ones, shuffled as we go. moved only
+// remembers the slots that don't hold their own number, so a space costs
+// nothing until it's used. Codes from finished games go to quarantined
+// first, so an old link doesn't drop someone into a stranger's new game.
+type codeSpace struct {
+	pattern []string // the alphabet for each character
+	size int
+	remaining int
+	moved map[int]int // slot -> number, where they differ
+	inUse int
+	quarantined []freedCode // oldest first
+}
+
+type freedCode struct {
+	n int
+	at time.Time
+}
+
+var codeSpaces []*codeSpace // shortest first
+var checkAlphabet string
+
+// setUpGameCodes reads the code flags (before any games are made)
+func setUpGameCodes() error {
+	letters, digits := *codeLetters, *codeDigits
+	if letters == "" || digits == "" || *codePattern == "" {
+		return errors.New("game codes need a pattern, letters and digits")
+	}
+	// every code has to spell out a different number (see codeFor), and
+	// lookups upper-case what people type
+	if err := checkCodeAlphabet("codeletters", letters, 'A', 'Z'); err != nil {
+		return err
+	}
+	if err := checkCodeAlphabet("codedigits", digits, '0', '9'); err != nil {
+		return err
+	}
+	either := letters + digits
+	checkAlphabet = either
+
+	var pattern []string
+	for _, p := range *codePattern {
+		switch p {
+		case 'L':
+			pattern = append(pattern, letters)
+		case 'D':
+			pattern = append(pattern, digits)
+		case 'E':
+			pattern = append(pattern, either)
+		default:
+			return fmt.Errorf("game code pattern can only have L, D and E in it, not %q", p)
+		}
+	}
+
+	codeSpaces = nil
+	for extra := 0; extra <= maxExtraDigits; extra++ {
+		size := 1
+		for _, alphabet := range pattern {
+			size *= len(alphabet)
+		}
+		codeSpaces = append(codeSpaces, &codeSpace{pattern: pattern, size: size, remaining: size, moved: make(map[int]int)})
+		pattern = append(pattern[:len(pattern):len(pattern)], digits)
+	}
+
+	if *codeBlocklist != "" {
+		f, err := os.Open(*codeBlocklist)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		words := bufio.NewScanner(f)
+		for words.Scan() {
+			if w := strings.TrimSpace(words.Text()); w != "" {
+				blocklist = append(blocklist, strings.ToUpper(w))
+			}
+		}
+		if err := words.Err(); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("          game codes look like: %s (%d of them before they get longer)\n", codeFor(codeSpaces[0].pattern, 0), codeSpaces[0].size)
+	return nil
+}
+
+func checkCodeAlphabet(name, alphabet string, from, to rune) error {
+	for i, ch := range alphabet {
+		if ch < from || ch > to {
+			return fmt.Errorf("-%s can only have %c to %c in it, not %q", name, from, to, ch)
+		}
+		if strings.IndexRune(alphabet[:i], ch) >= 0 {
+			return fmt.Errorf("-%s has %c in it twice", name, ch)
+		}
+	}
+	return nil
+}
+
+// newGameCode hands out an unused code (CALLER HOLDS gamesMutex)
 func newGameCode() (string, error) {
-	if codesProduced == maxCodes { // see comments below
-		return "", errors.New("All possible game codes are in use!")
+	for _, s := range codeSpaces { // before counting who's crowded
+		s.timeServed()
+	}
+	for _, s := range codeSpaces { // the shortest codes that aren't too crowded
+		if float64(s.inUse + len(s.quarantined)) < *codeCrowded * float64(s.size) {
+			if code, ok := s.take(); ok {
+				return code, nil
+			}
+		}
+	}
+	for _, s := range codeSpaces { // they're all crowded. anything will do
+		if code, ok := s.take(); ok {
+			return code, nil
+		}
+	}
+	return "", ErrNoCodes
+}
+
+// releaseGameCode puts a finished game's code in quarantine (CALLER HOLDS gamesMutex)
+func releaseGameCode(code string) {
+	for _, s := range codeSpaces {
+		if n, ok := numberFor(s.pattern, code); ok {
+			s.inUse--
+			s.quarantined = append(s.quarantined, freedCode{n: n, at: time.Now()})
+			return
+		}
+	}
+}
+
+func (s *codeSpace) slot(i int) int {
+	if n, moved := s.moved[i]; moved {
+		return n
+	}
+	return i
+}
+
+// timeServed frees the quarantined codes that have sat out long enough
+func (s *codeSpace) timeServed() {
+	for len(s.quarantined) > 0 && time.Since(s.quarantined[0].at) >= *codeQuarantine {
+		s.free(s.quarantined[0].n)
+		s.quarantined = s.quarantined[1:]
+	}
+}
+
+func (s *codeSpace) take() (string, bool) {
+	s.timeServed()
+	if s.remaining == 0 && len(s.quarantined) > 0 { // better an old code than no game
+		s.free(s.quarantined[0].n)
+		s.quarantined = s.quarantined[1:]
+	}
+
+	for s.remaining > 0 {
+		i := random.Intn(s.remaining)
+		n := s.slot(i)
+		last := s.remaining-1
+		if i != last {
+			s.moved[i] = s.slot(last)
+		}
+		delete(s.moved, last)
+		s.remaining--
+
+		code := codeFor(s.pattern, n)
+		if isRude(code) { // never hand this one out
+			continue
+		}
+		s.inUse++
+		return code, true
+	}
+	return "", false
+}
+
+func (s *codeSpace) free(n int) {
+	if n != s.remaining {
+		s.moved[s.remaining] = n
+	}
+	s.remaining++
+}
+
+func isRude(code string) bool {
+	code = readAsLetters.Replace(code)
+	for _, word := range blocklist {
+		if strings.Contains(code, word) {
+			return true
+		}
 	}
+	return false
+}
 
-	var r = func(s string) string {
-		return string(s[random.Intn(len(s))])
+// codeFor turns a number (0 to the size of the space - 1) into a code, reading
+// it in mixed radix: one digit per character, each in base len(its alphabet).
+// The check character (if there is one) goes on the end.
+func codeFor(pattern []string, n int) string {
+	code := make([]byte, len(pattern))
+	for i := len(pattern)-1; i >= 0; i-- {
+		code[i] = pattern[i][n % len(pattern[i])]
+		n /= len(pattern[i])
+	}
+	if *codeCheck {
+		return string(code) + string(checkCharacter(string(code)))
 	}
+	return string(code)
+}
 
-	var randomCode string
-	for {
-		randomCode = r(letters)+r(either)+r(digits)+r(digits)
-		if _, exists := games[randomCode]; !exists {
-			break
+func numberFor(pattern []string, code string) (int, bool) {
+	if *codeCheck {
+		if !checksOut(code) {
+			return 0, false
+		}
+		code = code[:len(code)-1]
+	}
+	if len(code) != len(pattern) {
+		return 0, false
+	}
+	n := 0
+	for i := range pattern {
+		d := strings.IndexByte(pattern[i], code[i])
+		if d < 0 {
+			return 0, false
 		}
+		n = n*len(pattern[i]) + d
 	}
-	codesProduced++
-	return randomCode, nil
+	return n, true
+}
+
+// checkCharacter is Luhn mod N over checkAlphabet: it catches any one
+// mistyped character and most swapped pairs (all of them, if N is odd).
+func checkCharacter(code string) byte {
+	n := len(checkAlphabet)
+	sum := 0
+	double := true // from the right, starting with the last character
+	for i := len(code)-1; i >= 0; i-- {
+		d := strings.IndexByte(checkAlphabet, code[i])
+		if d < 0 {
+			return 0
+		}
+		if double {
+			d = doubled(d, n)
+		}
+		sum += d
+		double = !double
+	}
+	return checkAlphabet[(n - sum%n) % n]
+}
+
+// doubled is d's weight in every other place. Luhn adds up the digits of
+// 2d in base n, which only gives every d a different weight when n is even.
+// When it's odd, 2d mod n does.
+func doubled(d, n int) int {
+	if n%2 == 1 {
+		return 2*d % n
+	}
+	return 2*d/n + 2*d%n
+}
+
+func checksOut(code string) bool {
+	return len(code) > 1 && checkCharacter(code[:len(code)-1]) == code[len(code)-1]
+}
+
+// nearestGameCode is the live code closest to a code that doesn't check out,
+// if there's one close enough to be what they meant (CALLER HOLDS gamesMutex)
+func nearestGameCode(code string) (string, bool) {
+	if !*codeCheck || checksOut(code) { // not a typo. the game's just over
+		return "", false
+	}
+	best, bestDistance := "", 3 // more than 2 mistakes is a different code
+	for live := range games {
+		if d := editDistance(code, live); d < bestDistance {
+			best, bestDistance = live, d
+		}
+	}
+	return best, best != ""
+}
+
+func editDistance(a, b string) int { // Levenshtein, counting a swapped pair as one
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = least(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = least(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func least(ns ...int) int {
+	l := ns[0]
+	for _, n := range ns[1:] {
+		if n < l { l = n }
+	}
+	return l
 }
 
 
@@ -37,9 +343,9 @@ func newGameCode() (string, error) {
 
 DESCRIPTION:
 
-A game code is 4 characters long
+By default a game code is 5 characters long
 (L = letter, D = digit, E = either letter or digit)
-  LEDD
+  LEDD and a check character
 Certain letters and digits have been excluded as they
 can be ambiguous depending on fonts, letter case, and
 the characters to the left and right. For instance:
@@ -47,15 +353,24 @@ the characters to the left and right. For instance:
 is not a possible game code.
 A game code can have at most 2 letters in a row (LE)
 --I'm hoping that this will preclude any rude words
-from being inside a code.
+from being inside a code. (Just in case, codes are
+checked against a blocklist too.)
+
+All of that can be changed with the -code flags.
+
+The check character is worked out from the rest of the
+code. If someone types a code that doesn't check out,
+they made a typo, and we can suggest the live code that's
+closest to what they typed. If it checks out but there's
+no such game, the game's probably over.
 
 
 
 
 NOTE:
-This code is less than ideal.
+This code used to be less than ideal.
 
-Assume: 
+Assume:
 
 letters = "ACEFHJKMNPRTWXY"
  digits = "23456789"
@@ -63,30 +378,19 @@ letters = "ACEFHJKMNPRTWXY"
 and the format is LEDD
 
 That gives you 22,080 unique game codes.
-Now, assume that there are 22,075 games currently.
-That means that 99.98% of the possible game codes have
-been used, and that the random string generator only
-has a 5 in 22080 chance (0.02%) of generating a usable
-game code.
-
-So--grossly inefficient. BUT, on a modern computer,
-generating all 22,080 possible codes, in random order,
-only takes around 150ms - 300ms. In fact, this is
-exactly how I would sort a deck of cards on a TI-85
-when I was a kid (and that /did/ take a while).
-
-One idea for a faster generator would be to instead
-generate a random number 0 -> 22079 (inclusive). And
-also have a array of 22080 bools handy ([22080]bool).
-When you generate a number, say, 273, if that index in
-your array is false, make it true (code has been
-generated) and then convert the number into our 4
-character code.  Then, the next time through, if you
-randomly generated 273 again, you go to 273 in the
-array, see that it's true (used), and you simply go
-forward in the array until you find an index that is
-false, and use that number instead. This prevents the
-constant collisions that occur with my algorithm above.
+The old generator made random codes until it hit one that
+wasn't in use. With 22,075 games going, it only had a
+5 in 22080 chance (0.02%) of generating a usable code
+each try. It also never gave codes back, so after 22,080
+games (ever), the server was full.
+
+Now every code has a number, and picking a random free
+code is picking a random free number (see codeSpace), so
+it takes the same time whether 1 or 22,079 codes are in
+use. When a game is deleted its code goes back, after
+sitting out for -codequarantine. And once half the codes
+are in use (-codecrowded), new games get codes with
+another digit on the end, so there's always plenty of room.
 
 You can test the speed of the generator above with
 the following code:
@@ -97,6 +401,8 @@ the following code:
 var games = make(map[string]struct{})
 
 func main() {
+   flag.Parse()
+   setUpGameCodes()
    start := time.Now()
    i := 0
    for {
@@ -115,4 +421,3 @@ func main() {
 }
 
 */
-
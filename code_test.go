@@ -0,0 +1,220 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// useCodes sets up game codes like the flags would, and puts them back after
+func useCodes(t *testing.T, pattern string, quarantine time.Duration) {
+	t.Helper()
+	oldPattern, oldQuarantine := *codePattern, *codeQuarantine
+	*codePattern, *codeQuarantine = pattern, quarantine
+	t.Cleanup(func() {
+		*codePattern, *codeQuarantine = oldPattern, oldQuarantine
+		setUpGameCodes()
+	})
+	if err := setUpGameCodes(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTakeAllReleaseTakeAgain(t *testing.T) {
+	useCodes(t, "LD", 0)
+	s := codeSpaces[0]
+
+	taken := make(map[string]bool)
+	for {
+		code, ok := s.take()
+		if !ok { break }
+		if taken[code] {
+			t.Fatalf("%s was handed out twice", code)
+		}
+		if isRude(code) {
+			t.Fatalf("%s was handed out", code)
+		}
+		taken[code] = true
+	}
+	if len(taken) == 0 || len(taken) > s.size || s.inUse != len(taken) {
+		t.Fatalf("took %d codes from a space of %d (%d in use)", len(taken), s.size, s.inUse)
+	}
+
+	for code := range taken {
+		releaseGameCode(code)
+	}
+	if s.inUse != 0 {
+		t.Fatalf("%d codes still in use after releasing them all", s.inUse)
+	}
+	again := make(map[string]bool)
+	for {
+		code, ok := s.take()
+		if !ok { break }
+		if !taken[code] || again[code] {
+			t.Fatalf("got %s back, which wasn't released (or came back twice)", code)
+		}
+		again[code] = true
+	}
+	if len(again) != len(taken) {
+		t.Fatalf("took %d codes the second time, %d the first", len(again), len(taken))
+	}
+}
+
+func TestQuarantinedCodesComeBack(t *testing.T) {
+	useCodes(t, "LD", 0)
+	short := len(codeFor(codeSpaces[0].pattern, 0))
+
+	for round := 0; round < 3; round++ { // fill the short codes till they're crowded, then let them all go
+		var codes []string
+		for {
+			code, err := newGameCode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(code) != short {
+				releaseGameCode(code)
+				break
+			}
+			codes = append(codes, code)
+		}
+		if len(codes) == 0 {
+			t.Fatalf("round %d: no short codes left (in use %d, quarantined %d)", round, codeSpaces[0].inUse, len(codeSpaces[0].quarantined))
+		}
+		for _, code := range codes {
+			releaseGameCode(code)
+		}
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	useCodes(t, "LD", time.Hour)
+	s := codeSpaces[0]
+
+	first, _ := s.take()
+	releaseGameCode(first)
+	for {
+		code, ok := s.take()
+		if !ok { break }
+		if code == first && len(s.quarantined) > 0 {
+			t.Fatalf("%s came back while it was still quarantined", code)
+		}
+		if code == first { // everything else is taken: better an old code than no game
+			return
+		}
+	}
+	t.Fatalf("%s never came back, even with every other code taken", first)
+}
+
+func TestCodeNumbers(t *testing.T) {
+	useCodes(t, "LEDD", 0)
+	s := codeSpaces[0]
+	for n := 0; n < s.size; n += 7 {
+		code := codeFor(s.pattern, n)
+		if back, ok := numberFor(s.pattern, code); !ok || back != n {
+			t.Fatalf("%d -> %s -> %d, %v", n, code, back, ok)
+		}
+	}
+}
+
+func TestCheckCharacterCatchesTypos(t *testing.T) {
+	oldDigits := *codeDigits
+	defer func() { *codeDigits = oldDigits }()
+	for _, digits := range []string{"23456789", "2345678"} { // an odd and an even number of characters
+		*codeDigits = digits
+		useCodes(t, "LEDD", 0)
+		checkCatchesTypos(t, codeSpaces[0])
+	}
+}
+
+func checkCatchesTypos(t *testing.T, s *codeSpace) {
+	for n := 0; n < s.size; n += 11 {
+		code := codeFor(s.pattern, n)
+		if !checksOut(code) {
+			t.Fatalf("%s doesn't check out", code)
+		}
+		for i := 0; i < len(code); i++ { // every one character mistake
+			for j := 0; j < len(checkAlphabet); j++ {
+				if checkAlphabet[j] == code[i] { continue }
+				typo := code[:i] + string(checkAlphabet[j]) + code[i+1:]
+				if checksOut(typo) {
+					t.Fatalf("%s checks out, but it's a typo of %s", typo, code)
+				}
+			}
+		}
+		for i := 0; i+1 < len(code)-1; i++ { // swapped neighbours (not counting the check character)
+			if code[i] == code[i+1] { continue }
+			swapped := code[:i] + string(code[i+1]) + string(code[i]) + code[i+2:]
+			if checksOut(swapped) && !lumped(code[i], code[i+1]) {
+				t.Errorf("%s checks out, but it's %s swapped", swapped, code)
+			}
+		}
+	}
+}
+
+// lumped is whether the check character can't tell a and b swapped
+// (with an even number of characters, Luhn mod N misses a pair or two)
+func lumped(a, b byte) bool {
+	n := len(checkAlphabet)
+	da, db := indexIn(checkAlphabet, a), indexIn(checkAlphabet, b)
+	return (da+doubled(db, n)) % n == (db+doubled(da, n)) % n
+}
+
+func indexIn(s string, b byte) int {
+	for i := range s {
+		if s[i] == b { return i }
+	}
+	return -1
+}
+
+func TestNearestGameCode(t *testing.T) {
+	useCodes(t, "LEDD", 0)
+	live := codeFor(codeSpaces[0].pattern, 1234)
+	games = map[string]*game{live: nil}
+	t.Cleanup(func() { games = make(map[string]*game) })
+
+	typo := live[:1] + string(differentFrom(live[1])) + live[2:]
+	if got, ok := nearestGameCode(typo); !ok || got != live {
+		t.Errorf("nearestGameCode(%s) = %s, %v; want %s", typo, got, ok, live)
+	}
+	if got, ok := nearestGameCode(live); ok {
+		t.Errorf("nearestGameCode(%s) = %s, but it's a real code", live, got)
+	}
+	other := codeFor(codeSpaces[0].pattern, 0)
+	if got, ok := nearestGameCode(other); ok {
+		t.Errorf("nearestGameCode(%s) = %s, but it checks out (the game's just over)", other, got)
+	}
+	if got, ok := nearestGameCode("ZZZZZ"); ok {
+		t.Errorf("nearestGameCode(ZZZZZ) = %s, but that's nothing like %s", got, live)
+	}
+}
+
+func differentFrom(b byte) byte {
+	for i := range checkAlphabet {
+		if checkAlphabet[i] != b { return checkAlphabet[i] }
+	}
+	return b
+}
+
+func TestBadCodeAlphabets(t *testing.T) {
+	oldLetters, oldDigits := *codeLetters, *codeDigits
+	defer func() {
+		*codeLetters, *codeDigits = oldLetters, oldDigits
+		setUpGameCodes()
+	}()
+	tests := []struct {
+		letters, digits string
+		ok bool
+	}{
+		{"ACEF", "2345", true},
+		{"ACEA", "2345", false}, // a letter twice
+		{"ACEF", "2342", false}, // a digit twice
+		{"ACEf", "2345", false}, // lower case never matches what's typed
+		{"AC3F", "2345", false}, // a digit with the letters
+		{"ACEF", "23A5", false}, // a letter with the digits
+	}
+	for _, test := range tests {
+		*codeLetters, *codeDigits = test.letters, test.digits
+		if err := setUpGameCodes(); (err == nil) != test.ok {
+			t.Errorf("letters %q, digits %q: %v", test.letters, test.digits, err)
+		}
+	}
+}
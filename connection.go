@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/edmangimelli/hide-and-seek/protocol"
+)
+
+var sendQueue = flag.Int("sendqueue", 64, "msgs a player can fall behind by before they're disconnected")
+var writeTimeout = flag.Duration("writetimeout", 10*time.Second, "how long writing one msg to a player can take")
+var pingInterval = flag.Duration("pinginterval", 20*time.Second, "how often players are pinged")
+var readTimeout = flag.Duration("readtimeout", 45*time.Second, "how long a player can go without a msg or pong before they're disconnected")
+
+// a connection is one player's websocket, as seen from their game.
+// Games hand it msgs with send; its writer is the only thing that writes
+// to the socket. A game never waits on a player: if they stop reading and
+// their queue fills up, they're hung up on (and get the usual grace period).
+type connection struct {
+	out chan string // encoded msgs waiting to be written ("close" = disconnect)
+	closed chan struct{} // closed once nobody's reading out anymore
+	kicked chan struct{} // closed when we've given up on them (see hangUp)
+	hangUpOnce sync.Once
+	codec protocol.Codec
+
+	mutex sync.Mutex // guards who
+	who string // code/name, for the logs
+}
+
+func newConnection(codec protocol.Codec) *connection {
+	return &connection{
+		out: make(chan string, *sendQueue),
+		closed: make(chan struct{}),
+		kicked: make(chan struct{}),
+		codec: codec,
+	}
+}
+
+// send queues a msg for the player. Msgs for a connection that's gone are dropped.
+func (c *connection) send(rawMsg string) {
+	select {
+	case c.out <- rawMsg:
+	case <-c.closed:
+	default: // they've stopped reading
+		log.Printf("\n%s has fallen %d msgs behind. hanging up.\n", c.label(), cap(c.out))
+		c.hangUp()
+	}
+}
+
+// tell sends m however they speak
+func (c *connection) tell(m interface{}) {
+	for _, frame := range c.codec.Encode(m) {
+		c.send(frame)
+	}
+}
+
+// hangUp drops the connection without waiting for anything queued to be written.
+func (c *connection) hangUp() {
+	c.hangUpOnce.Do(func() { close(c.kicked) })
+}
+
+func (c *connection) setWho(code, name string) {
+	c.mutex.Lock()
+	c.who = code + "/" + name
+	c.mutex.Unlock()
+}
+
+func (c *connection) label() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.who
+}
+
+// listen sets up the read side: a player who's gone quiet (not even
+// answering pings) for readTimeout fails their next read, and gets
+// disconnected like anyone whose connection dropped.
+func listen(conn *websocket.Conn) {
+	conn.SetReadDeadline(time.Now().Add(*readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(*readTimeout))
+	})
+}
+
+// heard pushes the read deadline back after a msg from the player.
+func heard(conn *websocket.Conn) {
+	conn.SetReadDeadline(time.Now().Add(*readTimeout))
+}
+
+// write is the connection's writer: it runs until the connection is closed.
+// It also does the pinging.
+func (c *connection) write(conn *websocket.Conn) {
+	ping := time.NewTicker(*pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(*writeTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				log.Printf("\nping failed: %s\n", c.label())
+				conn.Close()
+			}
+		case rawMsg := <-c.out:
+			if rawMsg == "close" { // booted (or replaced). the game has already let go of us
+				conn.Close()
+				continue
+			}
+			if err := sendMsg(conn, c.label(), rawMsg); err != nil {
+				conn.Close() // the reader notices, and they're disconnected
+			}
+		case <-c.kicked:
+			conn.Close()
+			return
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+func sendMsg(conn *websocket.Conn, who string, msg string) error {
+	conn.SetWriteDeadline(time.Now().Add(*writeTimeout))
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		log.Printf("\nconn.WriteMessage failed:\nconnection:\n%s\nto: %s\nmsg:\n%s\n", conn.RemoteAddr().String(), who, msg)
+		return err
+	}
+	log.Printf("\n📝 message sent to %s:\n%s\n", who, msg)
+	return nil
+}
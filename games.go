@@ -0,0 +1,212 @@
+package main
+
+import (
+	"log"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edmangimelli/hide-and-seek/bot"
+	"github.com/edmangimelli/hide-and-seek/hideandseek"
+	"github.com/edmangimelli/hide-and-seek/protocol"
+)
+
+// Each game runs on its own goroutine, which is the only one that touches
+// the game's engine, conns and bots. Everyone else asks it to do things
+// with do. The only thing games share is the list of them.
+
+type game struct {
+	engine *hideandseek.Game
+	conns map[string]*connection // player name -> connection
+	bots map[string]bool
+
+	inbox chan func()
+	quit chan struct{} // closed when the game is over
+	over bool
+}
+
+var games = make(map[string]*game, 0)
+var gamesMutex = sync.Mutex{} // guards games (and game codes)
+
+func lookup(code string) (*game, bool) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+	g, exists := games[strings.ToUpper(strings.TrimSpace(code))]
+	return g, exists
+}
+
+// noSuchGame is what to tell someone looking for a game that isn't there
+func noSuchGame(code string) protocol.NoSuchGame {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+	nearest, _ := nearestGameCode(strings.ToUpper(strings.TrimSpace(code)))
+	return protocol.NoSuchGame{Code: code, DidYouMean: nearest}
+}
+
+// newGame makes a game, gives it a code and starts it running.
+func newGame(rules hideandseek.Ruleset) (*game, error) {
+	gamesMutex.Lock()
+	defer gamesMutex.Unlock()
+
+	code, err := newGameCode()
+	if err != nil {
+		return nil, err
+	}
+	if _, live := games[code]; live { // the code allocator's lost track
+		log.Printf("\nBUG: new game code %s is already in use\n", code)
+		return nil, ErrCodeInUse
+	}
+	engine, err := hideandseek.New(code, rules)
+	if err != nil {
+		releaseGameCode(code)
+		return nil, err
+	}
+	g := &game{
+		engine: engine,
+		conns: make(map[string]*connection),
+		bots: make(map[string]bool),
+		inbox: make(chan func()),
+		quit: make(chan struct{}),
+	}
+	games[code] = g
+	go g.run()
+	log.Printf("\nnew game created: %s\n", code)
+	return g, nil
+}
+
+func (g *game) run() {
+	defer close(g.quit)
+	defer func() { // a bug in one game shouldn't take the others down
+		if r := recover(); r != nil {
+			log.Printf("\nPANIC in game %s: %v\n%s", g.engine.Code(), r, debug.Stack())
+			g.abandon()
+		}
+	}()
+
+	for f := range g.inbox {
+		f()
+		if g.over {
+			return
+		}
+	}
+}
+
+// do runs f on the game's goroutine and waits for it.
+// It's false if the game was over before f got to run.
+func (g *game) do(f func()) bool {
+	done := make(chan struct{})
+	select {
+	case g.inbox <- func() { f(); close(done) }:
+	case <-g.quit:
+		return false
+	}
+	select {
+	case <-done:
+		return true
+	case <-g.quit:
+		select { // f might have been what ended it
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// deliver hands each event to its player's connection (GAME GOROUTINE)
+func (g *game) deliver(events []hideandseek.Event) {
+	needBot := false
+	defer func() {
+		if needBot && *autoBots {
+			d, _ := bot.ParseDifficulty(*autoBotLevel)
+			g.addBot(d)
+		}
+	}()
+
+	for _, e := range events {
+		if e.To == "" { // meant for us, not a player
+			switch m := e.Message.(type) {
+			case hideandseek.ReadyTimer:
+				g.after(m.After, hideandseek.BootUnready{Stage: m.Stage, Token: m.Token})
+			case hideandseek.GraceTimer:
+				g.after(m.After, hideandseek.GraceOver{Name: m.Name, Away: m.Away})
+			}
+			continue
+		}
+
+		c, exists := g.conns[e.To]
+		if !exists {
+			continue
+		}
+		switch m := e.Message.(type) {
+		case hideandseek.Boot:
+			c.send("close")
+			delete(g.conns, e.To)
+			delete(g.bots, e.To)
+			continue
+		case hideandseek.TooFewHiders:
+			needBot = needBot || !g.bots[e.To]
+		case hideandseek.RoundOver:
+			needBot = needBot || (m.CantContinue && !g.bots[e.To])
+		}
+		c.tell(e.Message)
+	}
+}
+
+// after runs cmd against the game once d has passed (unless the game's over by then)
+func (g *game) after(d time.Duration, cmd hideandseek.Command) {
+	time.AfterFunc(d, func() {
+		g.do(func() {
+			events, _ := g.engine.Handle(cmd)
+			g.deliver(events)
+			g.deleteIfEmpty()
+		})
+	})
+}
+
+func (g *game) leave(name string) { // GAME GOROUTINE
+	delete(g.conns, name)
+	events, _ := g.engine.Handle(hideandseek.Leave{Name: name})
+	g.deliver(events)
+	g.deleteIfEmpty()
+}
+
+func (g *game) disconnect(name string, c *connection) { // GAME GOROUTINE
+	if g.conns[name] != c { return } // already gone, or they're back on a new connection
+
+	delete(g.conns, name)
+	events, _ := g.engine.Handle(hideandseek.Disconnect{Name: name})
+	g.deliver(events)
+}
+
+func (g *game) deleteIfEmpty() { // GAME GOROUTINE
+	g.botsLeaveIfAlone()
+	if !g.engine.Empty() {
+		return
+	}
+	for _, c := range g.conns { // spectators
+		c.send("close")
+	}
+
+	gamesMutex.Lock()
+	delete(games, g.engine.Code())
+	releaseGameCode(g.engine.Code())
+	gamesMutex.Unlock()
+	g.over = true
+	log.Printf("\nGame deleted: %s\n", g.engine.Code())
+}
+
+// abandon hangs up on everyone and forgets the game (GAME GOROUTINE)
+func (g *game) abandon() {
+	for _, c := range g.conns {
+		c.hangUp()
+	}
+	gamesMutex.Lock()
+	if games[g.engine.Code()] == g {
+		delete(games, g.engine.Code())
+		releaseGameCode(g.engine.Code())
+	}
+	gamesMutex.Unlock()
+	g.over = true
+}
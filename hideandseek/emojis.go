@@ -0,0 +1,115 @@
+package hideandseek
+
+import (
+	"strings"
+	"log"
+	"unicode"
+	"unicode/utf8"
+)
+
+const seekerDot = "🔴" // what hiders see the seeker as
+const maxEmojiLength = 8 // runes: enough for a flag or a family
+var emojis = [][]rune{[]rune("😛👽💩🤖👾👻😸🙈👶🐶🦁🐴🦄🐮🐷⛄🎃🌛🐐🐪🐘🐭🐰🐿🐨🐼🐔🐣🐧🕊🐸🐊🐢🐍🐳🐟🐡🐙🦀🐌🐜🐝🐞🕷"), []rune("🐚⛷🚣🏎👌👃💋🕶🎒👟👑🎓💎🍇🍉🍋🍍🍎🍓🍅🍄🍞🧀🍔🍟🍕🌭🍿🍦🍩🍪🎂🍫🍭☕🍽🗽🎠💈🚂🚌🚲🛢⚓⏰☂🎈📖🕯💡📷📺💾☎🎷🔔🏐🔮🎮🎲📡💼📬☯⚛🏁"), []rune("🂡🂢🂣🂤🂥🂦🂧🂨🂩🂪🂫🂭🂮🂱🂲🂳🂴🂵🂶🂷🂸🂹🂺🂻🂽🂾🃁🃂🃃🃄🃅🃆🃇🃈🃉🃊🃋🃍🃎🃑🃒🃓🃔🃕🃖🃗🃘🃙🃚🃛🃝🃞🂿"), []rune("🁣🁤🁥🁦🁧🁨🁩🁪🁫🁬🁭🁮🁯🁰🁱🁲🁳🁴🁵🁶🁷🁸🁹🁺🁻🁼🁽🁾🁿🂀🂁🂂🂃🂄🂅🂆🂇🂈🂉🂊🂋🂌🂍🂎🂏🂐🂑🂒🂓"), []rune("①②③④⑤⑥⑦⑧⑨⑩⑪⑫⑬⑭⑮⑯⑰⑱⑲⑳")}
+
+var maxPlayersPerGame int
+
+func init() {
+	for _, set := range emojis {
+		maxPlayersPerGame += len(set)
+	}
+	log.Printf("  maximum number of players per game: %v\n", maxPlayersPerGame)
+}
+
+func randomEmoji(g *Game, name string) string {
+
+	if special, ok := specialAvatar(g, name); ok { // see themes.go
+		g.specialInUse[special] = true
+		return special
+	}
+
+	grabFromSet := func(i int) rune {
+		len := len(emojis[i])
+		if len == 1 { // the loop below doesn't work if len is 1
+			if g.usedEmojis[i][0] {
+				return rune(0)
+			}
+			g.usedEmojis[i][0] = true
+			return emojis[i][0]
+		}
+
+		r := g.random.Intn(len)
+		startingPoint := r
+		for g.usedEmojis[i][r] { // starting at r, cycle through runes
+			r++
+			if r == len {
+				r = 0
+			}
+			if r == startingPoint { // all the way round: the set's full
+				return rune(0);
+			}
+		}
+		g.usedEmojis[i][r] = true
+		return emojis[i][r]
+	}
+
+	var r rune
+	for i := 0; i < len(emojis) && r == 0; i++ {
+		r = grabFromSet(i)
+	}
+
+	return string(r) // join makes sure there's never more players than emojis (see Ruleset.MaxPlayers)
+}
+
+// pickEmoji gives them the emoji they asked for, if nobody
+// in the game has it already, or else a random one.
+func pickEmoji(g *Game, name, want string) string {
+	if want == "" || !emojiAvailable(g, want) {
+		return randomEmoji(g, name)
+	}
+	if isSpecialAvatar(want) {
+		g.specialInUse[want] = true
+	} else if i, r, inSets := findEmoji(want); inSets {
+		g.usedEmojis[i][r] = true // so randomEmoji doesn't hand it out too
+	}
+	return want
+}
+
+func emojiAvailable(g *Game, emoji string) bool {
+	if utf8.RuneCountInString(emoji) > maxEmojiLength || emoji == seekerDot || strings.IndexFunc(emoji, unicode.IsSpace) >= 0 {
+		return false
+	}
+	if isSpecialAvatar(emoji) {
+		return !g.specialInUse[emoji]
+	}
+	for _, p := range g.players {
+		if p.emoji == emoji {
+			return false
+		}
+	}
+	return true
+}
+
+// findEmoji says where an emoji is in emojis, if it's there
+func findEmoji(emoji string) (int, int, bool) {
+	rs := []rune(emoji)
+	if len(rs) != 1 {
+		return 0, 0, false
+	}
+	for i, set := range emojis {
+		for r := range set {
+			if set[r] == rs[0] {
+				return i, r, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// freeEmoji lets someone else have a leaving player's emoji
+func freeEmoji(g *Game, emoji string) {
+	if isSpecialAvatar(emoji) {
+		delete(g.specialInUse, emoji)
+	} else if i, r, inSets := findEmoji(emoji); inSets {
+		g.usedEmojis[i][r] = false
+	}
+}
@@ -0,0 +1,28 @@
+package hideandseek
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEveryoneGetsTheirOwnEmoji(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		g, _ := New("TEST", Ruleset{Seed: seed})
+		seen := make(map[string]string)
+		for i := 0; i < maxPlayersPerGame; i++ { // through every set, till they're all used up
+			name := fmt.Sprintf("player %d", i)
+			handle(t, g, Join{Name: name})
+			e := g.players[name].emoji
+			if e == "" || e == "\x00" {
+				t.Fatalf("seed %d: %s got no emoji", seed, name)
+			}
+			if other, taken := seen[e]; taken {
+				t.Fatalf("seed %d: %s and %s both got %s", seed, other, name, e)
+			}
+			seen[e] = name
+		}
+		if _, err := g.Handle(Join{Name: "one too many"}); err != ErrGameFull {
+			t.Errorf("seed %d: joining with every emoji taken: %v", seed, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package hideandseek
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"math/rand"
+)
+
+// A Forest is a grid of cells, each some kind of Terrain (see terrain.go).
+type Forest [][]rune
+
+// CanReach is whether one move can get from (fromRow, fromCol) to (row, col):
+// it has to be in reach (see Ruleset.inReach), and not on or through
+// anything you can't Cross.
+func (r Ruleset) CanReach(f Forest, fromRow, fromCol, row, col int) bool {
+	if !r.inReach(fromRow, fromCol, row, col) || !TerrainOf(f[row][col]).Cross {
+		return false
+	}
+	if abs(row-fromRow) <= 1 && abs(col-fromCol) <= 1 { // one step can't go through anything
+		return true
+	}
+
+	// breadth first, a step at a time, around whatever's in the way
+	type cell struct{ row, col int }
+	seen := map[cell]bool{{fromRow, fromCol}: true}
+	frontier := []cell{{fromRow, fromCol}}
+	for step := 0; step < r.MoveRadius && len(frontier) > 0; step++ {
+		var next []cell
+		for _, here := range frontier {
+			for dr := -1; dr <= 1; dr++ {
+				for dc := -1; dc <= 1; dc++ {
+					n := cell{here.row+dr, here.col+dc}
+					if (dr == 0 && dc == 0) || (r.NoDiagonals && dr != 0 && dc != 0) || seen[n] { continue }
+					if n.row < 0 || n.row >= len(f) || n.col < 0 || n.col >= len(f[n.row]) || !TerrainOf(f[n.row][n.col]).Cross { continue }
+					if n.row == row && n.col == col { return true }
+					seen[n] = true
+					next = append(next, n)
+				}
+			}
+		}
+		frontier = next
+	}
+	return false
+}
+
+// On the wire (JSON) a forest is a list of rows, each row a string.
+func (f Forest) MarshalJSON() ([]byte, error) {
+	rows := make([]string, len(f))
+	for r := range f {
+		rows[r] = string(f[r])
+	}
+	return json.Marshal(rows)
+}
+
+func (f *Forest) UnmarshalJSON(data []byte) error {
+	var rows []string
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return err
+	}
+	*f = make(Forest, len(rows))
+	for r := range rows {
+		(*f)[r] = []rune(rows[r])
+	}
+	return nil
+}
+
+// growForest is this round's forest, from the host's generator (see generators.go)
+func (g *Game) growForest(r *rand.Rand) Forest {
+	g.onMap = false
+	if g.custom != nil {
+		if err := g.custom.Validate(len(g.players)); err == nil {
+			g.onMap = true
+			return g.custom.Forest.Copy() // trees get cut down during the round
+		} else {
+			log.Printf("\n%s: can't use the host's map: %s\n", g.code, err)
+		}
+	}
+
+	plan := ForestPlan{Trees: len(g.players) * g.rules.TreesPerPlayer, Aspect: g.aspect(), Glyphs: g.theme().Trees}
+	plan.glyphs = rand.New(rand.NewSource(r.Int63()))
+	name := g.rules.Forest
+	if name == "" { name = g.theme().Forest }
+	if name == "" { name = DefaultForest }
+
+	f := generators[name].Grow(plan, r)
+	if !usable(f, len(g.players)) {
+		log.Printf("\nBUG: %s forest generator made a forest nobody can play in. using random.\n", name)
+		f = randomForest{}.Grow(plan, r)
+	}
+	if g.rules.Terrain {
+		f = placeTerrain(f, r)
+	}
+	return f
+}
+
+// Copy is a forest whose trees can be cut down without touching f's.
+func (f Forest) Copy() Forest {
+	c := make(Forest, len(f))
+	for r := range f {
+		c[r] = append([]rune(nil), f[r]...)
+	}
+	return c
+}
+
+func randomLineOfTrees(resultLength int, tree func() rune) []rune {
+	result := make([]rune, resultLength)
+	for i := 0; i < resultLength; i++ {
+		result[i] = tree()
+	}
+	return result
+}
+
+// notes on treesPerRow:
+//
+// Our forest is a grid. Judging from my phone and my wife's phone,
+// phones are typically a 1:2 rectangle (height is double the width).
+// I want a grid as close to that as possible.
+// (That's the default aspect. These days players tell us their screen's
+// aspect when they join, and the forest fits them: see Game.aspect.
+// A Ruleset can ask for something else.)
+// NOTE! The return value will not necessarily evenly divide your
+// number of trees. That was not a goal. For example, a forest with
+// 30 trees will have 8 rows with 4 trees in the first 7 rows, and
+// 2 straggler trees in the last row--not 6 rows with 5 each.
+// I think 8 rows of 4 with an incomplete row satisfies our goal of a 1:2 rectangle. 
+
+func treesPerRow(trees int, aspect float64) int { // this took a lot of tweeking and
+	w := 0                                          // testing to get it just right :P
+	t := float64(trees)
+	if trees < 50 { // a little fatter at the beginning
+		for {
+			if float64(w*w)*aspect >= t { return w }
+			w++
+		}
+	} else {
+		for {
+			if float64(w*w)*aspect == t { return w }
+			if float64(w*w)*aspect > t { return w-1 }
+			w++
+		}
+	}
+}
+
+/* testing
+func main() {
+	for trees := 0; trees <= 200; trees++ {
+		fmt.Printf("%3d   %2d\n", trees, treesPerRow(trees, 2))
+	}
+}
+*/
+
+
+
+
+// aspect is the shape of forest that suits everyone: the host's, if they
+// picked one, or else a compromise between everyone's screens. It's the
+// geometric mean, so a 1:2 phone and a 2:1 laptop meet at square.
+func (g *Game) aspect() float64 {
+	if g.rules.Aspect != 0 {
+		return g.rules.Aspect
+	}
+	logs, screens := 0.0, 0
+	for _, n := range g.names() { // in order, so it comes out the same to the last bit
+		p := g.players[n]
+		if p.aspect == 0 { continue }
+		logs += math.Log(p.aspect)
+		screens++
+	}
+	if screens == 0 {
+		return defaultAspect
+	}
+	return math.Exp(logs / float64(screens))
+}
+
+// screenAspect is a reported aspect we can use (0 if it's nonsense)
+func screenAspect(aspect float64) float64 {
+	switch {
+	case math.IsNaN(aspect) || aspect <= 0:
+		return 0
+	case aspect < minAspect:
+		return minAspect
+	case aspect > maxAspect:
+		return maxAspect
+	}
+	return aspect
+}
+
+func populateForest(g *Game, random *rand.Rand) {
+	for n := range g.players {
+		g.players[n].col = -1
+		g.players[n].row = -1
+	}
+
+	height := len(g.wood)
+	width := len(g.wood[0])
+
+	if g.onMap { // the map says where to start
+		spawns := random.Perm(len(g.custom.Spawns))
+		for _, n := range g.names() {
+			if len(spawns) == 0 { break }
+			s := g.custom.Spawns[spawns[0]]
+			spawns = spawns[1:]
+			g.players[n].row, g.players[n].col = s.Row, s.Col
+		}
+	}
+
+	for _, n := range g.names() {
+		if g.players[n].row >= 0 { continue } // spawned
+		var col, row int
+randomCoord:
+		for {
+			col = random.Intn(width)
+			row = random.Intn(height)
+
+			if !Hideable(g.wood[row][col]) { continue }
+			for m := range g.players { // technically you're comparing against yourself, but it doesn't matter
+				if col == g.players[m].col && row == g.players[m].row {
+					continue randomCoord
+				}
+			}
+			break
+		}
+		g.players[n].col = col
+		g.players[n].row = row
+	}
+}
+
@@ -0,0 +1,862 @@
+// Package hideandseek holds the rules of the game. It doesn't know anything
+// about websockets: a Game takes Commands and hands back Events, and it's up
+// to whoever is hosting the game to get those Events to the right players.
+//
+// A Game is not safe for concurrent use, but different Games can be used
+// from different goroutines.
+package hideandseek
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var random *rand.Rand
+func init() {
+	Seed(time.Now().UnixNano())
+}
+
+// Seed makes the games that follow repeatable: each game's seed comes from here (see seeds.go).
+func Seed(seed int64) {
+	random = rand.New(&lockedSource{source: rand.NewSource(seed)})
+}
+
+// lockedSource lets games on different goroutines share random.
+type lockedSource struct {
+	mutex sync.Mutex
+	source rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.source.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.source.Seed(seed)
+}
+
+const ReadyTimeout = 10*time.Second // players who aren't ready by then get booted
+const GracePeriod = 60*time.Second // how long a dropped player's seat is kept for them
+
+var (
+	ErrNameTaken = errors.New("name is taken")
+	ErrNotInGame = errors.New("not in this game")
+	ErrUnknownStage = errors.New("unknown ready stage")
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrBadToken = errors.New("no seat for that session token")
+	ErrGameFull = errors.New("game is full")
+	ErrStarted = errors.New("the game has already started")
+	ErrNotSeeker = errors.New("only the seeker can do that")
+
+	// rejected moves
+	ErrNoRound = errors.New("no round in progress")
+	ErrNotInForest = errors.New("you're not in the forest")
+	ErrOffForest = errors.New("that's off the forest")
+	ErrTooFar = errors.New("that's too far to move")
+	ErrWall = errors.New("you can't get there from here")
+	ErrNoTree = errors.New("hiders can only move to trees")
+	ErrOccupied = errors.New("someone's already there")
+)
+
+type player struct {
+	// round variables
+	seeker bool
+	found bool
+	ready map[string]bool
+	row, col int
+	movesThisRound int
+
+	// game variables
+	emoji string
+	aspect float64 // their screen's height / width (0: they didn't say)
+	bot bool
+	token string // session token for getting back in after a dropped connection
+	away int // non-zero while disconnected (see disconnect)
+	waiting bool
+	score int
+	totalMoves int
+	numberOfTimesHasBeenSeeker int
+	numberOfTimesHasBeenHider int
+	numberOfTimesHasEarnedSeeker int
+}
+
+type Game struct {
+	code string
+	rules Ruleset // filled in (see Ruleset.Filled)
+	host string // who started the game (or took over from them)
+	seed int64
+	random *rand.Rand // the game's own (see seeds.go)
+	roundSeed int64 // this round's
+	replay int64 // non-zero: the next round's seed
+	wood Forest
+	custom *Map // the host's map, if they uploaded one (see UseMap)
+	onMap bool // this round is on it
+	players map[string]*player
+	spectators map[string]bool // they see what a hider sees, and can't do anything
+	waitlist []waiter // first in line first (see waitlist.go)
+	inRound bool // false = seeker hasn't started the game
+	round int
+	usedEmojis [][]bool
+	specialInUse map[string]bool // theme avatars that are taken (see themes.go)
+	multiHiderRound bool
+	roundLive bool // setup has been sent and the round isn't over
+	going bool // everyone's been told "go!"
+	visited [][]bool // trees the seeker has been to this round
+	lastFound string // the hider found most recently this round
+	pendingBoots map[string]int // ready stage -> token of the ReadyTimer that's counting down
+	bootTokens int
+	awayTokens int
+
+	out []Event // collects events while a command is handled
+}
+
+const (
+	active = iota
+	found = iota
+	waiting = iota
+	waitingAndFound = iota
+)
+
+// New makes a game with the host's rules (see Ruleset.Validate).
+func New(code string, rules Ruleset) (*Game, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	g := &Game{
+		code: code,
+		rules: rules.Filled(),
+		players: make(map[string]*player),
+		spectators: make(map[string]bool),
+		usedEmojis: make([][]bool, len(emojis)),
+		specialInUse: make(map[string]bool),
+		pendingBoots: make(map[string]int),
+	}
+	g.random, g.seed = newGameRandom(rules.Seed)
+	for i := range g.usedEmojis {
+		g.usedEmojis[i] = make([]bool, len(emojis[i]))
+	}
+	return g, nil
+}
+
+func (g *Game) Code() string {
+	return g.code
+}
+
+func (g *Game) Rules() Ruleset {
+	return g.rules
+}
+
+func (g *Game) Empty() bool {
+	return len(g.players) == 0
+}
+
+func (g *Game) Has(name string) bool { // players only, not spectators
+	_, exists := g.players[name]
+	return exists
+}
+
+// MovesThisRound is how many moves name has made since the last setup.
+func (g *Game) MovesThisRound(name string) int {
+	if p, exists := g.players[name]; exists {
+		return p.movesThisRound
+	}
+	return 0
+}
+
+func (g *Game) Players() []string {
+	names := make([]string, 0, len(g.players))
+	for n := range g.players {
+		names = append(names, n)
+	}
+	return names
+}
+
+// Handle applies c to the game and returns everything players need to be told.
+// An error means c was rejected and the game is unchanged.
+func (g *Game) Handle(c Command) ([]Event, error) {
+	g.out = nil
+	var err error
+
+	switch c := c.(type) {
+	case Join:
+		err = g.join(c.Name, c.Emoji, c.Aspect, c.Bot)
+	case Move:
+		err = g.move(c.Name, c.Row, c.Col)
+	case Start:
+		err = g.start(c.Name)
+	case Ready:
+		err = g.ready(c.Name, c.Stage)
+	case Spectate:
+		err = g.spectate(c.Name)
+	case Leave:
+		if g.spectators[c.Name] {
+			g.stopSpectating(c.Name)
+		} else if g.onWaitlist(c.Name) {
+			g.leaveWaitlist(c.Name)
+		} else {
+			g.leave(c.Name)
+		}
+	case Disconnect:
+		if g.onWaitlist(c.Name) { // nothing to hold a seat in
+			g.leaveWaitlist(c.Name)
+		} else if g.spectators[c.Name] {
+			g.stopSpectating(c.Name)
+		} else {
+			g.disconnect(c.Name)
+		}
+	case Resume:
+		err = g.resume(c.Name, c.Token)
+	case GraceOver:
+		g.graceOver(c.Name, c.Away)
+	case BootUnready:
+		g.bootUnready(c.Stage, c.Token)
+	case Replay:
+		err = g.replayRound(c.Name, c.Seed)
+	default:
+		err = ErrUnknownCommand
+	}
+	if err == nil {
+		g.admitWaiting() // in case that freed a seat
+	}
+
+	out := g.out
+	g.out = nil
+	return out, err
+}
+
+func (g *Game) send(to string, m Message) {
+	g.out = append(g.out, Event{To: to, Message: m})
+}
+
+func (g *Game) join(name, wantEmoji string, aspect float64, bot bool) error {
+	if _, exists := g.players[name]; exists || g.spectators[name] || g.onWaitlist(name) {
+		return ErrNameTaken
+	}
+	if g.Full() {
+		if !g.rules.Waitlist {
+			return ErrGameFull
+		}
+		g.joinWaitlist(name, wantEmoji, aspect, bot)
+		return nil
+	}
+
+	host := len(g.players) == 0
+	if host { g.host = name }
+	emoji := pickEmoji(g, name, wantEmoji)
+	token := newToken()
+	g.players[name] = &player{
+		emoji: emoji,
+		aspect: screenAspect(aspect),
+		bot: bot,
+		token: token,
+		seeker: host,
+		waiting: g.inRound,
+		row: -1,
+		col: -1,
+		ready: make(map[string]bool),
+	}
+	g.players[name].ready[ReadyToGo] = false
+	g.players[name].ready[ReadyForNextSetup] = false
+	log.Printf("\nplayer has joined: %s/%s\n", g.code, name)
+
+	if host {
+		g.send(name, Initialized{Code: g.code, Emoji: emoji, Name: name, Token: token})
+		return nil
+	}
+
+	for n := range g.players { // tell other players
+		if n != name { // don't need to send the message to yourself
+			g.send(n, Joined{Emoji: emoji, Name: name})
+		}
+	}
+	g.showSpectators(Joined{Emoji: emoji, Name: name})
+
+	g.send(name, g.wait(name))
+	if g.host == "" && !bot { // only bots were left
+		g.host = name
+		g.send(name, NowHost{})
+		if !g.inRound { // and bots don't press Start
+			for _, p := range g.players {
+				p.seeker = false
+			}
+			g.players[name].seeker = true
+			g.send(name, SeekerLeft{})
+		}
+	}
+	return nil
+}
+
+func (g *Game) wait(name string) Wait {
+	p := g.players[name]
+	w := Wait{NextRound: g.inRound, Code: g.code, Emoji: p.emoji, Name: name, Token: p.token}
+	for n, other := range g.players {
+		if n != name {
+			w.Others = append(w.Others, Avatar{Emoji: other.emoji, Name: n})
+		}
+	}
+	return w
+}
+
+func (g *Game) move(name string, row, col int) error {
+	mover, exists := g.players[name]
+	if !exists {
+		return ErrNotInGame
+	}
+	if err := g.checkMove(mover, row, col); err != nil {
+		log.Printf("\n%s/%s can't move to (%d, %d): %s\n", g.code, name, row, col, err)
+		return err
+	}
+
+	if mover.seeker {
+		for _, occ := range occupants(row, col, g) {
+			g.players[occ].found = true
+			g.lastFound = occ
+			if g.rules.Scoring == ScoreFinds {
+				mover.score++
+			}
+			winner := g.reportWinnerIfThereIsOne()
+			if winner != "" {
+				mover.movesThisRound++
+				mover.totalMoves++
+				if g.multiHiderRound {
+					g.players[winner].score++
+				}
+				return nil
+			}
+			for n, p := range g.players { // tell non-waiting players
+				if p.waiting || n == name { continue }
+				g.send(n, Found{Emoji: g.players[occ].emoji, Name: occ, Row: row, Col: col})
+			}
+			g.showSpectators(Found{Emoji: g.players[occ].emoji, Name: occ, Row: row, Col: col})
+		}
+	}
+
+	for n, p := range g.players { // tell non-waiting players
+		if p.waiting { continue }
+		g.send(n, Moved{Emoji: mover.emoji, FromRow: mover.row, FromCol: mover.col, ToRow: row, ToCol: col})
+	}
+	g.showSpectators(Moved{Emoji: mover.emoji, FromRow: mover.row, FromCol: mover.col, ToRow: row, ToCol: col})
+
+	mover.movesThisRound++
+	mover.totalMoves++
+	mover.row = row
+	mover.col = col
+
+	if mover.seeker {
+		g.seekerVisits(row, col)
+	}
+	g.lookAround()
+	return nil
+}
+
+// checkMove enforces the movement rules:
+// stay within the move radius (see Ruleset.inReach), stay on the forest,
+// don't go on or through anything you can't cross (see Terrain), hiders need
+// somewhere with room to hide, and only the seeker can move onto someone.
+func (g *Game) checkMove(p *player, row, col int) error {
+	switch {
+	case !g.inRound || !g.roundLive || !g.going || g.wood == nil: // not till "go!", and not once it's over
+		return ErrNoRound
+	case p.found || p.waiting || p.row < 0 || p.col < 0:
+		return ErrNotInForest
+	case row < 0 || row >= len(g.wood) || col < 0 || col >= len(g.wood[row]):
+		return ErrOffForest
+	case !g.rules.inReach(p.row, p.col, row, col) || (row == p.row && col == p.col):
+		return ErrTooFar
+	case !g.rules.CanReach(g.wood, p.row, p.col, row, col):
+		return ErrWall
+	case !p.seeker && !Hideable(g.wood[row][col]):
+		return ErrNoTree
+	case !p.seeker && len(occupants(row, col, g)) >= TerrainOf(g.wood[row][col]).Room:
+		return ErrOccupied
+	}
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 { return -n }
+	return n
+}
+
+// seekerVisits marks a tree as visited. Once the seeker has
+// been to every tree, each tree they step on gets cut down.
+func (g *Game) seekerVisits(row, col int) {
+	if !Hideable(g.wood[row][col]) { return }
+
+	g.visited[row][col] = true
+
+	for r := range g.wood {
+		for c := range g.wood[r] {
+			if Hideable(g.wood[r][c]) && !g.visited[r][c] {
+				return
+			}
+		}
+	}
+
+	g.wood[row][col] = ' '
+	for n, p := range g.players { // tell non-waiting players
+		if p.waiting { continue }
+		g.send(n, TreeRemoved{Row: row, Col: col})
+	}
+	g.showSpectators(TreeRemoved{Row: row, Col: col})
+}
+
+func (g *Game) start(name string) error {
+	p, exists := g.players[name]
+	switch {
+	case !exists:
+		return ErrNotInGame
+	case g.inRound:
+		return ErrStarted
+	case !p.seeker && !noSeeker(g):
+		return ErrNotSeeker
+	}
+	g.inRound = true
+	g.newSetup()
+	return nil
+}
+
+func (g *Game) ready(name, stage string) error {
+	if stage != ReadyToGo && stage != ReadyForNextSetup {
+		return ErrUnknownStage
+	}
+	if g.spectators[name] { // nobody waits on spectators
+		return nil
+	}
+	p, exists := g.players[name]
+	if !exists {
+		return ErrNotInGame
+	}
+
+	if (stage == ReadyToGo && !g.roundLive) || (stage == ReadyForNextSetup && !g.inRound) {
+		return ErrNoRound // nothing to be ready for
+	}
+
+	if stage == ReadyToGo && g.going { // they're back from a dropped connection mid-round
+		g.send(name, Go{})
+		return nil
+	}
+
+	if _, counting := g.pendingBoots[stage]; !counting {
+		log.Printf("\n%s: first \"%s\" msg received.\n", g.code, stage)
+		g.bootTokens++
+		g.pendingBoots[stage] = g.bootTokens
+		g.send("", ReadyTimer{Stage: stage, Token: g.bootTokens, After: g.rules.readyTimeout()})
+	}
+
+	p.ready[stage] = true
+
+	if everyonesReady(stage, g) {
+		log.Printf("\n%s: everyone responded \"%s\".\n      booting not-ready players CANCELLED.\n", g.code, stage)
+		delete(g.pendingBoots, stage)
+		g.everyoneIsReady(stage)
+	}
+	return nil
+}
+
+func (g *Game) everyoneIsReady(stage string) {
+	switch stage {
+	case ReadyToGo:
+		g.going = true
+		for n, p := range g.players {
+			p.ready[stage] = false
+			g.send(n, Go{})
+		}
+		g.showSpectators(Go{})
+		g.lookAround()
+	case ReadyForNextSetup:
+		g.newSetup()
+	}
+}
+
+func (g *Game) bootUnready(stage string, token int) {
+	if g.pendingBoots[stage] != token {
+		log.Printf("\n%s: bootNotReadyPlayers: cancelled. (%s)\n", g.code, stage)
+		return
+	}
+	delete(g.pendingBoots, stage)
+	log.Printf("\n%s: bootNotReadyPlayers: BOOTING. (%s)\n", g.code, stage)
+
+	for n, p := range g.players {
+		if !p.ready[stage] && p.away == 0 { // seats of dropped players are kept
+			log.Printf("\nbooting: %s/%s\n", g.code, n)
+			g.send(n, Boot{})
+			g.leave(n)
+		}
+	}
+
+	if !g.Empty() {
+		g.everyoneIsReady(stage)
+	}
+}
+
+func (g *Game) leave(name string) {
+	if _, exists := g.players[name]; !exists { return }
+
+	row := g.players[name].row
+	col := g.players[name].col
+	emoji := g.players[name].emoji
+	wasSeeker := g.players[name].seeker
+	gonePlayer := profilePlayer(g.players[name])
+
+	delete(g.players, name)
+	freeEmoji(g, emoji)
+	if name == g.host {
+		defer g.newHost() // once there's a new seeker, so it can be them
+	}
+	log.Printf("\nPlayer deleted: %s/%s\n", g.code, name)
+
+	actives, founds, waitings, waitingAndFounds := profilePlayers(g)
+	totalPlayers := actives + founds + waitings + waitingAndFounds
+	if waitingAndFounds > 0 {
+		log.Printf("\nBUG: some players are both waiting and found.\n")
+	}
+	waitings += waitingAndFounds
+
+	if len(g.players) == 0 {
+		return
+	}
+
+	if !g.inRound {
+		if wasSeeker {
+			n, _ := randomlyAppointSeeker(g)
+			g.send(n, SeekerLeft{})
+		} else {
+			for n := range g.players {
+				g.send(n, Left{Emoji: emoji, Name: name})
+			}
+		}
+		g.showSpectators(Left{Emoji: emoji, Name: name})
+		return
+	}
+
+	seekerLeft := func() {
+		for n, p := range g.players {
+			g.send(n, RoundOver{Reason: ReasonSeekerLeft, NowSeeker: p.seeker})
+		}
+		g.showSpectators(RoundOver{Reason: ReasonSeekerLeft})
+	}
+
+	switch gonePlayer {
+	case active:
+		if wasSeeker || actives < 2 {
+			g.endRound() // one way or another
+		}
+		if wasSeeker { // seeker left
+			n, _ := randomlyAppointSeeker(g)
+			if totalPlayers == 1 {
+				g.send(n, RoundOver{Reason: ReasonSeekerLeft, NowSeeker: true, CantContinue: true})
+				g.showSpectators(RoundOver{Reason: ReasonSeekerLeft, CantContinue: true})
+				g.inRound = false
+			} else {
+				seekerLeft()
+			}
+		} else { // hider left
+			switch actives {
+			case 0: // should be an impossible case
+				log.Printf("\nBUG: Impossible case. Round continued with 1 active player and then they left.\n")
+				if totalPlayers > 1 {
+					for _, p := range g.players {
+						p.seeker = false
+					}
+					randomlyAppointSeeker(g)
+					seekerLeft()
+				} else {
+					for n, p := range g.players {
+						p.seeker = true
+						g.send(n, RoundOver{Reason: ReasonSeekerLeft, NowSeeker: true, CantContinue: true})
+					}
+					g.showSpectators(RoundOver{Reason: ReasonSeekerLeft, CantContinue: true})
+					g.inRound = false
+				}
+			case 1: // seeker is alone
+				if (founds + waitings) > 0 {
+					for n := range g.players {
+						g.send(n, RoundOver{Reason: ReasonTooFewHiders})
+					}
+					g.showSpectators(RoundOver{Reason: ReasonTooFewHiders})
+					// note: seeker does not change
+				} else {
+					for n := range g.players { // only 1 player
+						g.send(n, RoundOver{Reason: ReasonTooFewHiders, CantContinue: true})
+					}
+					g.showSpectators(RoundOver{Reason: ReasonTooFewHiders, CantContinue: true})
+					g.inRound = false
+				}
+			default: // seeker is still in the round, and there's at least 1 hider
+				winner := g.reportWinnerIfThereIsOne()
+				if winner == "" { // there may be an automatic winner (multiHiderRound and only 1 hider left)
+					for n := range g.players {
+						g.send(n, Left{Emoji: emoji, Name: name, Hiding: true, Row: row, Col: col})
+					}
+					g.showSpectators(Left{Emoji: emoji, Name: name, Hiding: true, Row: row, Col: col})
+				}
+			}
+		}
+	case found, waiting, waitingAndFound:
+		for n := range g.players {
+			g.send(n, Left{Emoji: emoji, Name: name})
+		}
+		g.showSpectators(Left{Emoji: emoji, Name: name})
+	}
+}
+
+func seekerEmoji(g *Game) string {
+	for n := range g.players {
+		if g.players[n].seeker {
+			return g.players[n].emoji
+		}
+	}
+	return ""
+}
+
+func occupants(row int, col int, g *Game) []string {
+	var there []string
+	for n := range g.players {
+		if !g.players[n].found && !g.players[n].waiting && g.players[n].row == row && g.players[n].col == col {
+				there = append(there, n)
+		}
+	}
+	return there
+}
+
+func onlyOneHiderLeft(g *Game) string {
+	notFound := 0
+	last := ""
+
+	for n, p := range g.players {
+		if !p.seeker && !p.found && !p.waiting {
+			notFound++
+			last = n
+		}
+	}
+
+	if notFound == 1 {
+		return last
+	} else {
+		return ""
+	}
+}
+
+func everyonesReady(desc string, g *Game) bool {
+	for _, p := range g.players {
+		if !p.ready[desc] && p.away == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func everyonesFound(g *Game) bool {
+	for _, p := range g.players {
+		if p.seeker || p.waiting { continue }
+		if !p.found {
+			return false
+		}
+	}
+	return true
+}
+
+func numberOfWaitingToJoinPlayers(g *Game) int {
+	waiting := 0
+	for _, p := range g.players {
+		if p.waiting { waiting++ }
+	}
+	return waiting
+}
+
+func (g *Game) newSetup() {
+
+	if len(g.players) < 2 {
+		for n, p := range g.players { // tell only player
+			g.send(n, TooFewHiders{})
+			p.seeker = true // not sure if this is redundant
+		}
+		g.inRound = false // they Start again once someone joins
+		return
+	}
+
+	g.multiHiderRound = len(g.players) > 2
+
+	//if there's no seeker (seeker left)
+	if noSeeker(g) { randomlyAppointSeeker(g) }
+
+	g.round++
+	r, seed := g.roundRandom()
+	g.roundSeed = seed
+	g.wood = g.growForest(r)
+
+	populateForest(g, r) // everyone's given a random row and col
+	g.roundLive = true
+	g.going = false
+	g.lastFound = ""
+
+	g.visited = make([][]bool, len(g.wood))
+	for r := range g.visited {
+		g.visited[r] = make([]bool, len(g.wood[r]))
+	}
+	for _, p := range g.players {
+		if p.seeker { g.visited[p.row][p.col] = true }
+	}
+
+	for _, p := range g.players {
+		p.found = false;
+		p.ready[ReadyForNextSetup] = false;
+		p.waiting = false;
+		p.movesThisRound = 0
+		if p.seeker {
+			p.numberOfTimesHasBeenSeeker++
+		} else {
+			p.numberOfTimesHasBeenHider++
+		}
+	}
+
+	setup := g.setup()
+	for n := range g.players { // tell everyone
+		g.send(n, setup)
+	}
+	g.showSpectators(setup)
+}
+
+// setup describes the round as it stands: everyone who's still in the forest and where they are
+func (g *Game) setup() Setup {
+	s := Setup{Seeker: seekerEmoji(g), Forest: g.wood, Rules: g.rules, Seed: g.roundSeed}
+	for n, p := range g.players {
+		if p.found || p.waiting { continue }
+		s.Players = append(s.Players, Placement{Emoji: p.emoji, Name: n, Row: p.row, Col: p.col, Score: p.score})
+	}
+	return s
+}
+
+func (g *Game) endRound() {
+	g.roundLive = false
+	g.going = false
+}
+
+// noSeeker is whether the game needs a seeker appointed. If it's in a state
+// it should never be in (two seekers, or one still waiting to join), it says
+// so in the log and puts it right rather than take the whole server down.
+func noSeeker(g *Game) bool {
+	seeker := ""
+	for _, n := range g.names() {
+		p := g.players[n]
+		if !p.seeker { continue }
+		switch {
+		case p.waiting:
+			log.Printf("\nBUG: %s/%s is seeker but waiting to join. appointing someone else.\n", g.code, n)
+			p.seeker = false
+		case seeker != "":
+			log.Printf("\nBUG: %s has too many seekers (%s and %s). keeping %s.\n", g.code, seeker, n, seeker)
+			p.seeker = false
+		default:
+			seeker = n
+		}
+	}
+	return seeker == ""
+}
+
+// randomlyAppointSeeker picks someone who's in the round (or, if nobody is,
+// anyone). Before the first round it's someone who'll press Start: a person
+// who's here, if there is one.
+func randomlyAppointSeeker(g *Game) (string, *player) {
+	log.Println("Randomly appointing seeker!")
+	candidates := g.prefer(func(p *player) bool { return !p.waiting })
+	if !g.inRound {
+		candidates = g.prefer(func(p *player) bool { return !p.bot && p.away == 0 }, func(p *player) bool { return !p.bot })
+	}
+	n := candidates[g.random.Intn(len(candidates))]
+	g.players[n].seeker = true
+	return n, g.players[n]
+}
+
+// prefer is the names of the players the first test likes, or if it likes
+// nobody, the second, and so on. If none of them like anybody, it's everyone.
+func (g *Game) prefer(tests ...func(p *player) bool) []string {
+	for _, test := range tests {
+		var liked []string
+		for _, n := range g.names() {
+			if test(g.players[n]) {
+				liked = append(liked, n)
+			}
+		}
+		if len(liked) > 0 {
+			return liked
+		}
+	}
+	return g.names()
+}
+
+func (g *Game) reportWinnerIfThereIsOne() string {
+
+	if g.multiHiderRound {
+		var last string
+		switch g.rules.WinWhen {
+		case WinAllFound:
+			if everyonesFound(g) { last = g.lastFound }
+		default:
+			last = onlyOneHiderLeft(g)
+		}
+		if last != "" {
+			g.endRound()
+			for n, p := range g.players {
+				if p.seeker { p.seeker = false }
+				g.send(n, Winner{Emoji: g.players[last].emoji, Name: last})
+			}
+			g.showSpectators(Winner{Emoji: g.players[last].emoji, Name: last})
+			g.players[last].seeker = true
+			return last
+		}
+	} else {
+		if everyonesFound(g) {
+			g.endRound()
+			var seeker, hider string
+			for n, p := range g.players {
+				if p.seeker { seeker = n }
+				if p.found  { hider  = n }
+				g.send(n, RoundOver{Reason: ReasonTwoPlayerGame})
+			}
+			g.showSpectators(RoundOver{Reason: ReasonTwoPlayerGame})
+			g.players[seeker].seeker = false
+			g.players[hider].seeker = true
+			return hider
+		}
+	}
+	return ""
+}
+
+func profilePlayer(p *player) int {
+	switch {
+	case !p.found && !p.waiting:
+		return active
+	case !p.found &&  p.waiting:
+		return waiting
+	case  p.found && !p.waiting:
+		return found
+	}
+	//    p.found &&  p.waiting:
+	return waitingAndFound
+}
+
+func profilePlayers(g *Game) (int, int, int, int) {
+	var actives, waitings, founds, waitingAndFounds int
+	for _, p := range g.players {
+		switch profilePlayer(p) {
+		case active:
+			actives++
+		case waiting:
+			waitings++
+		case found:
+			founds++
+		case waitingAndFound:
+			waitingAndFounds++
+		}
+	}
+	return actives, founds, waitings, waitingAndFounds
+}
@@ -0,0 +1,370 @@
+package hideandseek
+
+import (
+	"reflect"
+	"testing"
+)
+
+func handle(t *testing.T, g *Game, c Command) []Event {
+	t.Helper()
+	events, err := g.Handle(c)
+	if err != nil {
+		t.Fatalf("%#v: %s", c, err)
+	}
+	return events
+}
+
+// sent is whether to was sent a message like m
+func sent(events []Event, to string, m Message) bool {
+	for _, e := range events {
+		if e.To == to && reflect.TypeOf(e.Message) == reflect.TypeOf(m) {
+			return true
+		}
+	}
+	return false
+}
+
+func join(t *testing.T, g *Game, names ...string) {
+	t.Helper()
+	for _, n := range names {
+		handle(t, g, Join{Name: n})
+	}
+}
+
+// play starts a round and gets it to "go!", then puts everyone where at says
+// on forest (T is a tree; see Terrain for the rest). The first name is the seeker.
+func play(t *testing.T, rules Ruleset, forest []string, at map[string][2]int, names ...string) *Game {
+	t.Helper()
+	g, err := New("TEST", rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	join(t, g, names...)
+	handle(t, g, Start{Name: names[0]})
+	for _, n := range names {
+		handle(t, g, Ready{Name: n, Stage: ReadyToGo})
+	}
+	if !g.going {
+		t.Fatal("no go after everyone was ready")
+	}
+
+	g.wood = make(Forest, len(forest))
+	g.visited = make([][]bool, len(forest))
+	for r := range forest {
+		g.wood[r] = []rune(forest[r])
+		g.visited[r] = make([]bool, len(g.wood[r]))
+	}
+	for n, rc := range at {
+		g.players[n].row, g.players[n].col = rc[0], rc[1]
+		if g.players[n].seeker {
+			g.visited[rc[0]][rc[1]] = true
+		}
+	}
+	return g
+}
+
+func TestJoin(t *testing.T) {
+	g, _ := New("TEST", Ruleset{MaxPlayers: 2})
+
+	events := handle(t, g, Join{Name: "host"})
+	if !sent(events, "host", Initialized{}) || !g.players["host"].seeker {
+		t.Errorf("the first player should get Initialized and be seeker: %v", events)
+	}
+	events = handle(t, g, Join{Name: "guest"})
+	if !sent(events, "guest", Wait{}) || !sent(events, "host", Joined{}) || g.players["guest"].seeker {
+		t.Errorf("the second player should get Wait, and the host Joined: %v", events)
+	}
+	if _, err := g.Handle(Join{Name: "guest"}); err != ErrNameTaken {
+		t.Errorf("joining with a taken name: %v", err)
+	}
+	if _, err := g.Handle(Join{Name: "third"}); err != ErrGameFull {
+		t.Errorf("joining a full game: %v", err)
+	}
+}
+
+func TestStart(t *testing.T) {
+	g, _ := New("TEST", Ruleset{})
+	join(t, g, "seeker")
+	if events := handle(t, g, Start{Name: "seeker"}); !sent(events, "seeker", TooFewHiders{}) || g.inRound {
+		t.Errorf("starting alone: %v", events)
+	}
+
+	join(t, g, "hider")
+	if _, err := g.Handle(Start{Name: "hider"}); err != ErrNotSeeker {
+		t.Errorf("hider starting: %v", err)
+	}
+	if events := handle(t, g, Start{Name: "seeker"}); !sent(events, "hider", Setup{}) || !sent(events, "seeker", Setup{}) {
+		t.Errorf("no setup: %v", events)
+	}
+	round := g.round
+	if _, err := g.Handle(Start{Name: "seeker"}); err != ErrStarted || g.round != round {
+		t.Errorf("starting again mid-round: %v", err)
+	}
+}
+
+func TestMovesWaitForGo(t *testing.T) {
+	g, _ := New("TEST", Ruleset{})
+	join(t, g, "seeker", "hider")
+	handle(t, g, Start{Name: "seeker"})
+	p := g.players["hider"]
+	if _, err := g.Handle(Move{Name: "hider", Row: p.row, Col: p.col + 1}); err != ErrNoRound {
+		t.Errorf("moving before go: %v", err)
+	}
+	handle(t, g, Ready{Name: "seeker", Stage: ReadyToGo})
+	events := handle(t, g, Ready{Name: "hider", Stage: ReadyToGo})
+	if !sent(events, "seeker", Go{}) || !sent(events, "hider", Go{}) {
+		t.Errorf("everyone was ready, but no go: %v", events)
+	}
+}
+
+func TestCheckMove(t *testing.T) {
+	forest := []string{
+		"TT TT",
+		"T🌊TTT",
+		"TTTTT",
+	}
+	at := map[string][2]int{"s": {0, 0}, "a": {0, 1}, "b": {2, 4}}
+	tests := []struct {
+		name string
+		rules Ruleset
+		who string
+		row, col int
+		want error
+	}{
+		{"hider to a tree", Ruleset{}, "a", 1, 0, nil},
+		{"hider diagonally", Ruleset{}, "a", 1, 2, nil},
+		{"hider diagonally, no diagonals", Ruleset{NoDiagonals: true}, "a", 1, 2, ErrTooFar},
+		{"hider two away", Ruleset{}, "a", 2, 1, ErrTooFar},
+		{"hider two away, radius 2", Ruleset{MoveRadius: 2}, "a", 0, 3, nil},
+		{"staying put", Ruleset{}, "a", 0, 1, ErrTooFar},
+		{"off the forest", Ruleset{}, "a", -1, 1, ErrOffForest},
+		{"off the edge", Ruleset{}, "b", 2, 5, ErrOffForest},
+		{"hider to open ground", Ruleset{}, "a", 0, 2, ErrNoTree},
+		{"hider into the river", Ruleset{}, "a", 1, 1, ErrWall},
+		{"seeker into the river", Ruleset{}, "s", 1, 1, ErrWall},
+		{"hider onto the seeker", Ruleset{}, "a", 0, 0, ErrOccupied},
+		{"seeker onto a hider", Ruleset{}, "s", 0, 1, nil},
+		{"seeker to open ground", Ruleset{MoveRadius: 2}, "s", 0, 2, nil},
+		{"around the river", Ruleset{MoveRadius: 2}, "a", 2, 1, nil},
+		{"through the river", Ruleset{MoveRadius: 2}, "s", 2, 2, ErrWall}, // going around takes 3
+	}
+	for _, test := range tests {
+		g := play(t, test.rules, forest, at, "s", "a", "b")
+		if err := g.checkMove(g.players[test.who], test.row, test.col); err != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	g := play(t, Ruleset{}, forest, at, "s", "a", "b")
+	g.players["a"].found = true
+	if err := g.checkMove(g.players["a"], 1, 0); err != ErrNotInForest {
+		t.Errorf("found hider moving: got %v, want %v", err, ErrNotInForest)
+	}
+}
+
+func TestMove(t *testing.T) {
+	g := play(t, Ruleset{}, []string{"TTT", "TTT"}, map[string][2]int{"s": {0, 0}, "a": {1, 2}, "b": {0, 2}}, "s", "a", "b")
+	events := handle(t, g, Move{Name: "a", Row: 1, Col: 1})
+	for _, n := range []string{"s", "a", "b"} {
+		if !sent(events, n, Moved{}) {
+			t.Errorf("%s wasn't told about the move: %v", n, events)
+		}
+	}
+	if p := g.players["a"]; p.row != 1 || p.col != 1 || p.movesThisRound != 1 {
+		t.Errorf("a is at (%d, %d) after %d moves", p.row, p.col, p.movesThisRound)
+	}
+	if _, err := g.Handle(Move{Name: "nobody", Row: 0, Col: 1}); err != ErrNotInGame {
+		t.Errorf("moving someone who isn't playing: %v", err)
+	}
+}
+
+func TestSeekerVisits(t *testing.T) {
+	g := play(t, Ruleset{}, []string{"TTT"}, map[string][2]int{"s": {0, 0}, "a": {0, 2}, "b": {0, 2}}, "s", "a", "b")
+	g.players["b"].row, g.players["b"].col = -1, -1 // out of the way
+
+	g.seekerVisits(0, 1)
+	if !g.visited[0][1] || g.wood[0][1] == ' ' {
+		t.Errorf("a tree came down before the seeker had been everywhere")
+	}
+	g.seekerVisits(0, 2)
+	if g.wood[0][2] != ' ' || !sent(g.out, "a", TreeRemoved{}) {
+		t.Errorf("the seeker has been everywhere, but the tree they're on is still standing: %q", string(g.wood[0]))
+	}
+
+	g.out = nil
+	g.wood[0][1] = OpenGround
+	g.seekerVisits(0, 1) // nothing to cut down
+	if len(g.out) != 0 {
+		t.Errorf("visiting open ground: %v", g.out)
+	}
+}
+
+func TestTwoPlayerRound(t *testing.T) {
+	g := play(t, Ruleset{}, []string{"TT"}, map[string][2]int{"s": {0, 0}, "h": {0, 1}}, "s", "h")
+	events := handle(t, g, Move{Name: "s", Row: 0, Col: 1})
+	if !sent(events, "s", RoundOver{}) || !sent(events, "h", RoundOver{}) {
+		t.Errorf("the hider was found, but the round isn't over: %v", events)
+	}
+	if !g.players["h"].seeker || g.players["s"].seeker {
+		t.Errorf("the hider should be the next seeker")
+	}
+	if _, err := g.Handle(Move{Name: "h", Row: 0, Col: 0}); err != ErrNoRound {
+		t.Errorf("moving after the round's over: %v", err)
+	}
+}
+
+func TestWinner(t *testing.T) {
+	forest := []string{"TTTT"}
+	at := map[string][2]int{"s": {0, 0}, "a": {0, 1}, "b": {0, 3}}
+
+	g := play(t, Ruleset{}, forest, at, "s", "a", "b")
+	events := handle(t, g, Move{Name: "s", Row: 0, Col: 1})
+	if !sent(events, "s", Winner{}) || g.roundLive {
+		t.Errorf("one hider left, but no winner: %v", events)
+	}
+	if !g.players["b"].seeker || g.players["s"].seeker || g.players["b"].score != 1 {
+		t.Errorf("b won, so they should seek next (and have a point)")
+	}
+	if _, err := g.Handle(Move{Name: "b", Row: 0, Col: 2}); err != ErrNoRound {
+		t.Errorf("the new seeker moving before the next round: %v", err)
+	}
+
+	at["c"] = [2]int{0, 2}
+	g = play(t, Ruleset{WinWhen: WinAllFound, Scoring: ScoreFinds}, forest, at, "s", "a", "b", "c")
+	events = handle(t, g, Move{Name: "s", Row: 0, Col: 1})
+	if sent(events, "s", Winner{}) || !sent(events, "b", Found{}) {
+		t.Errorf("everyone has to be found: %v", events)
+	}
+	handle(t, g, Move{Name: "s", Row: 0, Col: 2})
+	events = handle(t, g, Move{Name: "s", Row: 0, Col: 3})
+	if !sent(events, "a", Winner{}) || !g.players["b"].seeker || g.players["s"].score != 3 {
+		t.Errorf("the last one found should win, and the seeker get a point a find: %v", events)
+	}
+	score := g.players["b"].score
+	if _, err := g.Handle(Move{Name: "b", Row: 0, Col: 2}); err != ErrNoRound || g.players["b"].score != score {
+		t.Errorf("the new seeker finding people between rounds: %v", err)
+	}
+}
+
+func TestNextRound(t *testing.T) {
+	g := play(t, Ruleset{}, []string{"TT"}, map[string][2]int{"s": {0, 0}, "h": {0, 1}}, "s", "h")
+	handle(t, g, Move{Name: "s", Row: 0, Col: 1})
+	handle(t, g, Ready{Name: "s", Stage: ReadyForNextSetup})
+	events := handle(t, g, Ready{Name: "h", Stage: ReadyForNextSetup})
+	if !sent(events, "s", Setup{}) || !g.roundLive || g.going {
+		t.Errorf("everyone was ready for the next round, but there's no setup: %v", events)
+	}
+	if _, err := g.Handle(Ready{Name: "h", Stage: "whenever"}); err != ErrUnknownStage {
+		t.Errorf("unknown stage: %v", err)
+	}
+}
+
+func TestLeave(t *testing.T) {
+	at := map[string][2]int{"s": {0, 0}, "a": {0, 1}, "b": {0, 2}}
+
+	g := play(t, Ruleset{}, []string{"TTT"}, at, "s", "a", "b")
+	events := handle(t, g, Leave{Name: "a"})
+	if !sent(events, "s", Winner{}) || g.Has("a") {
+		t.Errorf("a left, so b is the last hider: %v", events)
+	}
+
+	g = play(t, Ruleset{}, []string{"TTT"}, at, "s", "a", "b")
+	events = handle(t, g, Leave{Name: "s"})
+	if !sent(events, "a", RoundOver{}) || noSeeker(g) {
+		t.Errorf("the seeker left: %v", events)
+	}
+	if g.Host() == "s" {
+		t.Errorf("the host left, but nobody took over")
+	}
+
+	delete(at, "b")
+	g = play(t, Ruleset{}, []string{"TTT"}, at, "s", "a")
+	events = handle(t, g, Leave{Name: "a"})
+	if !sent(events, "s", RoundOver{}) || g.roundLive {
+		t.Errorf("the only hider left: %v", events)
+	}
+}
+
+func TestLateJoinerIsNeverSeeker(t *testing.T) {
+	for seed := int64(1); seed <= 50; seed++ {
+		g := play(t, Ruleset{Seed: seed}, []string{"TTT"}, map[string][2]int{"s": {0, 0}, "a": {0, 1}, "b": {0, 2}}, "s", "a", "b")
+		join(t, g, "late")
+		handle(t, g, Leave{Name: "s"})
+		if g.players["late"].seeker {
+			t.Fatalf("seed %d: someone still waiting to join was made seeker", seed)
+		}
+		for _, n := range g.names() {
+			handle(t, g, Ready{Name: n, Stage: ReadyForNextSetup})
+		}
+		if noSeeker(g) {
+			t.Fatalf("seed %d: nobody's seeker in the next round", seed)
+		}
+	}
+}
+
+func TestNoSeekerPutsThingsRight(t *testing.T) {
+	g, _ := New("TEST", Ruleset{})
+	join(t, g, "a", "b", "c")
+	g.players["b"].seeker = true // two seekers
+	if noSeeker(g) || g.players["b"].seeker {
+		t.Errorf("with two seekers, the first should be kept")
+	}
+	g.players["a"].waiting = true
+	if !noSeeker(g) || g.players["a"].seeker {
+		t.Errorf("a seeker who's waiting to join isn't a seeker")
+	}
+}
+
+func TestHostLeavesBeforeTheFirstRound(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		g, _ := New("TEST", Ruleset{Seed: seed})
+		join(t, g, "host")
+		handle(t, g, Join{Name: "Bot", Bot: true})
+		handle(t, g, Join{Name: "Bot 2", Bot: true})
+		join(t, g, "zoe")
+		events := handle(t, g, Leave{Name: "host"})
+		if !g.players["zoe"].seeker || g.Host() != "zoe" {
+			t.Fatalf("seed %d: the only person left should seek and host (host %q)", seed, g.Host())
+		}
+		if !sent(events, "zoe", SeekerLeft{}) || !sent(events, "zoe", NowHost{}) {
+			t.Errorf("seed %d: zoe wasn't told: %v", seed, events)
+		}
+		handle(t, g, Start{Name: "zoe"})
+	}
+
+	g, _ := New("TEST", Ruleset{})
+	join(t, g, "host")
+	handle(t, g, Join{Name: "Bot", Bot: true})
+	handle(t, g, Leave{Name: "host"})
+	if g.Host() != "" {
+		t.Errorf("a bot became host")
+	}
+	events := handle(t, g, Join{Name: "someone"})
+	if g.Host() != "someone" || !sent(events, "someone", NowHost{}) || !g.players["someone"].seeker {
+		t.Errorf("the first person to join a game of bots should host it, and seek: %v", events)
+	}
+	handle(t, g, Start{Name: "someone"})
+}
+
+func TestResumeBeforeTheFirstRound(t *testing.T) {
+	g, _ := New("TEST", Ruleset{})
+	join(t, g, "host", "guest")
+	g.players["host"].seeker, g.players["guest"].seeker = false, true // host was away when the seeker was picked
+
+	for _, n := range []string{"host", "guest"} {
+		handle(t, g, Disconnect{Name: n})
+		events := handle(t, g, Resume{Name: n, Token: g.players[n].token})
+		if sent(events, n, Initialized{}) || !sent(events, n, Wait{}) {
+			t.Errorf("%s isn't both host and seeker, so gets Wait, not Initialized: %v", n, events)
+		}
+		if sent(events, n, NowHost{}) != (n == "host") || sent(events, n, SeekerLeft{}) != (n == "guest") {
+			t.Errorf("%s wasn't told what they are: %v", n, events)
+		}
+	}
+
+	g.players["host"].seeker, g.players["guest"].seeker = true, false
+	handle(t, g, Disconnect{Name: "host"})
+	if events := handle(t, g, Resume{Name: "host", Token: g.players["host"].token}); !sent(events, "host", Initialized{}) {
+		t.Errorf("the host, who's seeker, should get Initialized: %v", events)
+	}
+}
@@ -0,0 +1,271 @@
+package hideandseek
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// A ForestGenerator lays out a round's forest. It can be any shape it likes,
+// as long as it's a rectangle (every row the same length, ' ' where there's
+// no tree, Wall where nobody can go) with at least plan.Trees trees in it.
+// The host picks one with Ruleset.Forest (or the theme does); it gets its own
+// random, the round's (see seeds.go).
+type ForestGenerator interface {
+	Grow(plan ForestPlan, random *rand.Rand) Forest
+}
+
+type ForestPlan struct {
+	Trees int // how many trees (places to hide) the round needs
+	Aspect float64 // height / width (see treesPerRow)
+	Glyphs []rune // what the trees look like (see Theme)
+
+	glyphs *rand.Rand // picks them, so the theme doesn't change the layout
+}
+
+// Tree is one of plan.Glyphs, picked at random. Generators should get their
+// trees from here rather than their random: the theme (so the number of
+// glyphs) can be different when a round is replayed, and then the rest of
+// the forest would come out different too.
+func (plan ForestPlan) Tree() rune {
+	if len(plan.Glyphs) == 1 {
+		return plan.Glyphs[0]
+	}
+	if plan.glyphs == nil {
+		return plan.Glyphs[random.Intn(len(plan.Glyphs))]
+	}
+	return plan.Glyphs[plan.glyphs.Intn(len(plan.Glyphs))]
+}
+
+const DefaultForest = "random"
+
+var ErrGeneratorExists = errors.New("there's already a forest generator with that name")
+
+var generators = map[string]ForestGenerator{
+	"random": randomForest{},
+	"groves": groves{},
+	"ring": ring{},
+	"maze": maze{},
+	"islands": islands{},
+	"indoor": indoor{},
+}
+
+// RegisterForestGenerator adds a generator hosts can pick by name.
+// Call it before any games are made.
+func RegisterForestGenerator(name string, f ForestGenerator) error {
+	if _, exists := generators[name]; exists {
+		return ErrGeneratorExists
+	}
+	generators[name] = f
+	return nil
+}
+
+// ForestGenerators lists every registered generator, in alphabetical order.
+func ForestGenerators() []string {
+	names := make([]string, 0, len(generators))
+	for n := range generators {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// randomForest is the original: a rectangle of trees, with a few blanks scattered about.
+type randomForest struct{}
+
+func (randomForest) Grow(plan ForestPlan, random *rand.Rand) Forest {
+	perRow := treesPerRow(plan.Trees, plan.Aspect)
+	rows := plan.Trees/perRow
+	if rows*perRow < plan.Trees { rows++ } // we might end up with too many trees
+	treesToRemove := rows*perRow - plan.Trees
+
+	f := make(Forest, rows)
+
+	for r := 0; r < rows; r++ { // make forest
+		f[r] = randomLineOfTrees(perRow, plan.Tree)
+	}
+
+	for t := 0; t < treesToRemove; t++ { // remove the extra trees
+		f[random.Intn(rows)][random.Intn(perRow)] = ' '
+	}
+
+	return f
+}
+
+// groves are clumps of trees with clearings between them.
+type groves struct{}
+
+func (groves) Grow(plan ForestPlan, random *rand.Rand) Forest {
+	rows, cols := gridFor(plan.Trees*8/5, plan.Aspect)
+	centers := make([][2]float64, 1 + plan.Trees/10)
+	for i := range centers {
+		centers[i] = [2]float64{random.Float64() * float64(rows), random.Float64() * float64(cols)}
+	}
+	return plant(rows, cols, plan, random, func(r, c int) float64 {
+		nearest := math.Inf(1)
+		for _, ctr := range centers {
+			nearest = math.Min(nearest, math.Hypot(float64(r)-ctr[0], float64(c)-ctr[1]))
+		}
+		return nearest + random.Float64()*1.5 // ragged edges
+	})
+}
+
+// ring is a band of trees around a clearing.
+type ring struct{}
+
+func (ring) Grow(plan ForestPlan, random *rand.Rand) Forest {
+	rows, cols := gridFor(plan.Trees*2, plan.Aspect)
+	midR, midC := float64(rows-1)/2, float64(cols-1)/2
+	return plant(rows, cols, plan, random, func(r, c int) float64 {
+		d := math.Hypot((float64(r)-midR)/(midR+0.5), (float64(c)-midC)/(midC+0.5)) // 1 at the edge
+		return math.Abs(d-0.7) + random.Float64()*0.1
+	})
+}
+
+// islands are a few separate woods with open ground between them. Hiders
+// can only cross if the move radius lets them jump the gap.
+type islands struct{}
+
+func (islands) Grow(plan ForestPlan, random *rand.Rand) Forest {
+	across, down := 2, 2
+	if plan.Trees < 12 { down = 1 } // too few trees for four islands
+	perIsland := (plan.Trees + across*down - 1) / (across*down)
+	blockRows, blockCols := gridFor(perIsland*3/2, plan.Aspect)
+	blockRows, blockCols = blockRows+2, blockCols+2 // a border of open ground
+	rows, cols := blockRows*down, blockCols*across
+
+	return plant(rows, cols, plan, random, func(r, c int) float64 {
+		br, bc := r%blockRows, c%blockCols
+		if br == 0 || bc == 0 || br == blockRows-1 || bc == blockCols-1 {
+			return math.Inf(1)
+		}
+		d := math.Hypot(float64(br)-float64(blockRows-1)/2, float64(bc)-float64(blockCols-1)/2)
+		return d + random.Float64()
+	})
+}
+
+// maze is paths of trees with open ground for walls. Hiders have to follow
+// the paths; the seeker can cut across.
+type maze struct{}
+
+func (maze) Grow(plan ForestPlan, random *rand.Rand) Forest {
+	cells := (plan.Trees + 2) / 2 // a tree per cell, and one for each passage between them
+	w := int(math.Ceil(math.Sqrt(float64(cells) / plan.Aspect)))
+	if w < 1 { w = 1 }
+	h := (cells + w - 1) / w
+
+	f := make(Forest, 2*h-1)
+	for r := range f {
+		f[r] = make([]rune, 2*w-1)
+		for c := range f[r] {
+			f[r][c] = ' '
+		}
+	}
+	tree := plan.Tree
+
+	// recursive backtracker, without the recursion
+	visited := make([][]bool, h)
+	for r := range visited {
+		visited[r] = make([]bool, w)
+	}
+	stack := [][2]int{{random.Intn(h), random.Intn(w)}}
+	visited[stack[0][0]][stack[0][1]] = true
+	f[2*stack[0][0]][2*stack[0][1]] = tree()
+	for len(stack) > 0 {
+		here := stack[len(stack)-1]
+		var next [][2]int
+		for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			r, c := here[0]+d[0], here[1]+d[1]
+			if r >= 0 && r < h && c >= 0 && c < w && !visited[r][c] {
+				next = append(next, [2]int{r, c})
+			}
+		}
+		if len(next) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		n := next[random.Intn(len(next))]
+		visited[n[0]][n[1]] = true
+		f[here[0]+n[0]][here[1]+n[1]] = tree() // knock through
+		f[2*n[0]][2*n[1]] = tree()
+		stack = append(stack, n)
+	}
+	return f
+}
+
+// gridFor is the rows and cols of a grid with about cells cells in it
+func gridFor(cells int, aspect float64) (int, int) {
+	cols := treesPerRow(cells, aspect)
+	if cols < 1 { cols = 1 }
+	rows := (cells + cols - 1) / cols
+	return rows, cols
+}
+
+// plant puts trees on the plan.Trees cells that score lowest
+func plant(rows, cols int, plan ForestPlan, random *rand.Rand, score func(r, c int) float64) Forest {
+	type cell struct {
+		r, c int
+		score float64
+	}
+	cells := make([]cell, 0, rows*cols)
+	f := make(Forest, rows)
+	for r := range f {
+		f[r] = make([]rune, cols)
+		for c := range f[r] {
+			f[r][c] = ' '
+			cells = append(cells, cell{r, c, score(r, c)})
+		}
+	}
+	sort.SliceStable(cells, func(i, j int) bool { return cells[i].score < cells[j].score })
+	for i := 0; i < plan.Trees && i < len(cells) && !math.IsInf(cells[i].score, 1); i++ {
+		f[cells[i].r][cells[i].c] = plan.Tree()
+	}
+	return trim(f)
+}
+
+// trim cuts away empty rows and columns around the edges
+func trim(f Forest) Forest {
+	emptyRow := func(r int) bool {
+		for c := range f[r] {
+			if f[r][c] != ' ' { return false }
+		}
+		return true
+	}
+	emptyCol := func(c int) bool {
+		for r := range f {
+			if f[r][c] != ' ' { return false }
+		}
+		return true
+	}
+	for len(f) > 1 && emptyRow(0) { f = f[1:] }
+	for len(f) > 1 && emptyRow(len(f)-1) { f = f[:len(f)-1] }
+	for len(f[0]) > 1 && emptyCol(0) {
+		for r := range f { f[r] = f[r][1:] }
+	}
+	for len(f[0]) > 1 && emptyCol(len(f[0])-1) {
+		for r := range f { f[r] = f[r][:len(f[r])-1] }
+	}
+	return f
+}
+
+func countTrees(f Forest) int {
+	trees := 0
+	for r := range f {
+		for c := range f[r] {
+			if Hideable(f[r][c]) { trees++ }
+		}
+	}
+	return trees
+}
+
+// usable is whether a generator's forest is one populateForest and setup can work with
+func usable(f Forest, trees int) bool {
+	if len(f) == 0 || len(f[0]) == 0 || countTrees(f) < trees {
+		return false
+	}
+	for r := range f {
+		if len(f[r]) != len(f[0]) { return false }
+	}
+	return true
+}
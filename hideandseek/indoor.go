@@ -0,0 +1,83 @@
+package hideandseek
+
+import (
+	"math"
+	"math/rand"
+)
+
+// indoor is a building: two rows of rooms either side of a corridor, with
+// Walls between them. Every room has a door onto the corridor, and some have
+// one into the room below. Doors, the corridor and most of each room are
+// places to hide; the rest of the floor is open.
+type indoor struct{}
+
+const roomHeight = 3
+
+func (indoor) Grow(plan ForestPlan, random *rand.Rand) Forest {
+	var roomWidth, floors int
+	closest := math.Inf(1)
+	for w := 2; w <= 8; w++ { // the room width that gets the building closest to plan.Aspect
+		d := (plan.Trees + 2*w*roomHeight - 1) / (2*w*roomHeight)
+		rows, cols := d*(roomHeight+1)+1, 2*w+5
+		if off := math.Abs(float64(rows)/float64(cols) - plan.Aspect); off < closest {
+			closest, roomWidth, floors = off, w, d
+		}
+	}
+	rows, cols := floors*(roomHeight+1)+1, 2*roomWidth+5
+	hall := roomWidth+2 // the corridor's column
+	tree := plan.Tree
+
+	f := make(Forest, rows)
+	for r := range f {
+		f[r] = make([]rune, cols)
+		for c := range f[r] {
+			f[r][c] = Wall
+		}
+	}
+
+	var rooms, doors [][2]int
+	for floor := 0; floor < floors; floor++ {
+		top := floor*(roomHeight+1) + 1
+		for _, left := range []int{1, hall+2} {
+			for r := top; r < top+roomHeight; r++ {
+				for c := left; c < left+roomWidth; c++ {
+					f[r][c] = tree()
+					rooms = append(rooms, [2]int{r, c})
+				}
+			}
+			if left < hall { // onto the corridor
+				doors = append(doors, [2]int{top + random.Intn(roomHeight), hall-1})
+			} else {
+				doors = append(doors, [2]int{top + random.Intn(roomHeight), hall+1})
+			}
+			if floor < floors-1 && random.Intn(2) == 0 { // into the room below
+				doors = append(doors, [2]int{top + roomHeight, left + random.Intn(roomWidth)})
+			}
+		}
+	}
+	for r := 1; r < rows-1; r++ {
+		f[r][hall] = tree()
+	}
+	for _, d := range doors {
+		f[d[0]][d[1]] = tree()
+	}
+
+	// clear some floor, but never in front of a door (hiders have to be able to get through)
+	var spare [][2]int
+	for _, cell := range rooms {
+		byADoor := false
+		for _, d := range doors {
+			if abs(cell[0]-d[0]) <= 1 && abs(cell[1]-d[1]) <= 1 { byADoor = true }
+		}
+		if !byADoor {
+			spare = append(spare, cell)
+		}
+	}
+	trees := countTrees(f)
+	for _, i := range random.Perm(len(spare)) {
+		if trees <= plan.Trees { break }
+		f[spare[i][0]][spare[i][1]] = ' '
+		trees--
+	}
+	return f
+}
@@ -0,0 +1,175 @@
+package hideandseek
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Map is a forest somebody drew, to play in instead of a generated one.
+// In plain text it's a legend, the grid, and (if you like) where players
+// start. Blank lines and lines starting with // are ignored:
+//
+//	legend
+//	T 🌲
+//	t 🌳
+//	. open
+//	~ river
+//	# wall
+//	o rock
+//	* thicket
+//	map
+//	TTt.TT
+//	T~~~.T
+//	TTo*TT
+//	spawns
+//	0 0
+//	2 5
+//
+// Each legend line is a character from the grid and what it is: a terrain
+// (open, wall, river, rock or thicket) or anything else, which is a tree
+// that looks like that. Spawns are row col, from 0; players are put on them
+// (in random order) before anywhere else.
+type Map struct {
+	Forest Forest
+	Spawns []Spawn
+}
+
+type Spawn struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+const (
+	maxMapSize = 60 // rows or cols
+	minHidingPlacesPerPlayer = 2 // so hiders have somewhere to go
+)
+
+var ErrBadMap = errors.New("bad map")
+
+var legendTerrains = map[string]rune{
+	"open": OpenGround,
+	"wall": Wall,
+	"river": River,
+	"rock": Rock,
+	"thicket": Thicket,
+}
+
+// ParseMap reads a map in the plain text format (see Map).
+// It checks the map makes sense, but not that it's big enough (see Map.Validate).
+func ParseMap(text string) (Map, error) {
+	var m Map
+	legend := make(map[rune]rune)
+	section := ""
+	lines := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; lines.Scan(); n++ {
+		line := strings.TrimRight(lines.Text(), "\r")
+		bad := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%w: line %d: %s", ErrBadMap, n, fmt.Sprintf(format, args...))
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		switch strings.TrimSpace(line) {
+		case "legend", "map", "spawns":
+			section = strings.TrimSpace(line)
+			continue
+		}
+
+		switch section {
+		case "legend":
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				return m, bad("legend lines are a character and what it is")
+			}
+			key, size := utf8.DecodeRuneInString(fields[0]) // not line: it may be indented
+			if size != len(fields[0]) {
+				return m, bad("legend lines are a character and what it is")
+			}
+			if t, isTerrain := legendTerrains[fields[1]]; isTerrain {
+				legend[key] = t
+			} else if utf8.RuneCountInString(fields[1]) == 1 {
+				legend[key], _ = utf8.DecodeRuneInString(fields[1])
+			} else {
+				return m, bad("%q isn't a terrain or a single character", fields[1])
+			}
+		case "map":
+			var row []rune
+			for _, ch := range line {
+				cell, inLegend := legend[ch]
+				if !inLegend {
+					return m, bad("%q isn't in the legend", ch)
+				}
+				row = append(row, cell)
+			}
+			m.Forest = append(m.Forest, row)
+		case "spawns":
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				return m, bad("spawns are row col")
+			}
+			row, rowErr := strconv.Atoi(fields[0])
+			col, colErr := strconv.Atoi(fields[1])
+			if rowErr != nil || colErr != nil {
+				return m, bad("spawns are row col")
+			}
+			m.Spawns = append(m.Spawns, Spawn{Row: row, Col: col})
+		default:
+			return m, bad("expected legend, map or spawns")
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return m, fmt.Errorf("%w: %s", ErrBadMap, err)
+	}
+	return m, m.check()
+}
+
+// check is whether the map's the right shape, and its spawns are places to hide
+func (m Map) check() error {
+	switch {
+	case len(m.Forest) == 0:
+		return fmt.Errorf("%w: there's no map", ErrBadMap)
+	case len(m.Forest) > maxMapSize || len(m.Forest[0]) > maxMapSize:
+		return fmt.Errorf("%w: maps can be at most %d by %d", ErrBadMap, maxMapSize, maxMapSize)
+	}
+	for r := range m.Forest {
+		if len(m.Forest[r]) != len(m.Forest[0]) {
+			return fmt.Errorf("%w: row %d isn't as long as the first one", ErrBadMap, r)
+		}
+	}
+	taken := make(map[Spawn]bool)
+	for _, s := range m.Spawns {
+		if s.Row < 0 || s.Row >= len(m.Forest) || s.Col < 0 || s.Col >= len(m.Forest[0]) {
+			return fmt.Errorf("%w: spawn %d %d is off the map", ErrBadMap, s.Row, s.Col)
+		}
+		if !Hideable(m.Forest[s.Row][s.Col]) || taken[s] {
+			return fmt.Errorf("%w: spawn %d %d has to be a different place to hide", ErrBadMap, s.Row, s.Col)
+		}
+		taken[s] = true
+	}
+	return nil
+}
+
+// Validate is whether players can play on m.
+func (m Map) Validate(players int) error {
+	if err := m.check(); err != nil {
+		return err
+	}
+	if need := players * minHidingPlacesPerPlayer; countTrees(m.Forest) < need {
+		return fmt.Errorf("%w: %d players need at least %d places to hide", ErrBadMap, players, need)
+	}
+	return nil
+}
+
+// UseMap has every round played on m instead of a generated forest, for as
+// long as it's big enough for everyone (see Map.Validate).
+func (g *Game) UseMap(m Map) error {
+	if err := m.Validate(2); err != nil {
+		return err
+	}
+	g.custom = &m
+	return nil
+}
@@ -0,0 +1,126 @@
+package hideandseek
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMap(t *testing.T) {
+	m, err := ParseMap(`
+// a little one (an indented line is fine)
+legend
+T 🌲
+  t 🌳
+. open
+~ river
+# wall
+o rock
+* thicket
+map
+TTt.TT
+T~~~.T
+#To*TT
+spawns
+0 0
+2 5
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Forest{
+		[]rune("🌲🌲🌳 🌲🌲"),
+		{'🌲', River, River, River, OpenGround, '🌲'},
+		{Wall, '🌲', Rock, Thicket, '🌲', '🌲'},
+	}
+	if !reflect.DeepEqual(m.Forest, want) {
+		t.Errorf("forest:\n got %q\nwant %q", m.Forest, want)
+	}
+	if spawns := []Spawn{{0, 0}, {2, 5}}; !reflect.DeepEqual(m.Spawns, spawns) {
+		t.Errorf("spawns: got %v, want %v", m.Spawns, spawns)
+	}
+}
+
+func TestParseMapErrors(t *testing.T) {
+	const legend = "legend\nT 🌲\n. open\n~ river\n"
+	tests := []struct {
+		name string
+		text string
+		want string // in the error
+	}{
+		{"nothing", "", "there's no map"},
+		{"no legend", "map\nTT\n", "isn't in the legend"},
+		{"not in the legend", legend + "map\nTX\n", "isn't in the legend"},
+		{"legend with no meaning", "legend\nT\n", "a character and what it is"},
+		{"legend with a word", "legend\nT tree\n", "isn't a terrain or a single character"},
+		{"legend key too long", "legend\nTT 🌲\n", "a character and what it is"},
+		{"before any section", "TT\n", "expected legend, map or spawns"},
+		{"ragged rows", legend + "map\nTTT\nTT\n", "row 1 isn't as long"},
+		{"too big", legend + "map\n" + strings.Repeat("T", maxMapSize+1) + "\n", "at most"},
+		{"spawn isn't two numbers", legend + "map\nTT\nspawns\n0\n", "spawns are row col"},
+		{"spawn isn't numbers", legend + "map\nTT\nspawns\n0 x\n", "spawns are row col"},
+		{"spawn off the map", legend + "map\nTT\nspawns\n0 2\n", "off the map"},
+		{"spawn on open ground", legend + "map\nT.\nspawns\n0 1\n", "different place to hide"},
+		{"spawn in the river", legend + "map\nT~\nspawns\n0 1\n", "different place to hide"},
+		{"same spawn twice", legend + "map\nTT\nspawns\n0 0\n0 0\n", "different place to hide"},
+	}
+	for _, test := range tests {
+		_, err := ParseMap(test.text)
+		if !errors.Is(err, ErrBadMap) || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got %v, want an error with %q in it", test.name, err, test.want)
+		}
+	}
+}
+
+func TestMapValidate(t *testing.T) {
+	m, err := ParseMap("legend\nT 🌲\n. open\nmap\nTTTT\nTT..\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		players int
+		ok bool
+	}{
+		{2, true}, // 6 places to hide is enough for 3
+		{3, true},
+		{4, false},
+	}
+	for _, test := range tests {
+		if err := m.Validate(test.players); (err == nil) != test.ok {
+			t.Errorf("%d players on a map with %d places to hide: %v", test.players, countTrees(m.Forest), err)
+		}
+	}
+
+	g, _ := New("TEST", Ruleset{})
+	if err := g.UseMap(Map{Forest: Forest{{'🌲', ' '}}}); !errors.Is(err, ErrBadMap) {
+		t.Errorf("a map too small for two: %v", err)
+	}
+}
+
+func TestRoundOnAMap(t *testing.T) {
+	m, err := ParseMap("legend\nT 🌲\nmap\nTTTT\nTTTT\nspawns\n1 3\n0 0\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, _ := New("TEST", Ruleset{})
+	if err := g.UseMap(m); err != nil {
+		t.Fatal(err)
+	}
+	join(t, g, "a", "b")
+	s := setupIn(t, handle(t, g, Start{Name: "a"}))
+	if !reflect.DeepEqual(s.Forest, m.Forest) {
+		t.Errorf("the round isn't on the map: %q", s.Forest)
+	}
+	spawned := map[[2]int]bool{{1, 3}: true, {0, 0}: true}
+	for _, p := range s.Players {
+		if !spawned[[2]int{p.Row, p.Col}] {
+			t.Errorf("%s started at (%d, %d), not on a spawn", p.Name, p.Row, p.Col)
+		}
+	}
+
+	join(t, g, "c", "d", "e") // too many for the map: it's a generated forest till some leave
+	if g.growForest(g.random); g.onMap {
+		t.Errorf("5 players on a map with 8 places to hide")
+	}
+}
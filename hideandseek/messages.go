@@ -0,0 +1,234 @@
+package hideandseek
+
+import "time"
+
+// Commands are what players (or the program hosting the game) ask a Game to do.
+type Command interface {
+	command()
+}
+
+type Join struct { // the first player to join a game becomes its seeker
+	Name string
+	Emoji string // optional: the avatar they'd like (they get a random one if it's taken)
+	Aspect float64 // optional: their screen's height / width (see Game.aspect)
+	Bot bool // a program, not a person: it's never host, and never handed the seeker's Start button
+}
+
+type Move struct {
+	Name string
+	Row, Col int
+}
+
+type Start struct {
+	Name string
+}
+
+type Ready struct { // Stage is ReadyToGo or ReadyForNextSetup
+	Name string
+	Stage string
+}
+
+type Leave struct {
+	Name string
+}
+
+type Spectate struct { // watch without playing (Name can't be a player's)
+	Name string
+}
+
+type BootUnready struct { // send this when a ReadyTimer goes off
+	Stage string
+	Token int
+}
+
+type Disconnect struct { // their connection dropped; they may Resume
+	Name string
+}
+
+type Resume struct {
+	Name string
+	Token string // the session token from Initialized or Wait
+}
+
+type GraceOver struct { // send this when a GraceTimer goes off
+	Name string
+	Away int
+}
+
+type Replay struct { // host only: the next round is grown from Seed (0: this round's again)
+	Name string
+	Seed int64
+}
+
+func (Join) command()        {}
+func (Move) command()        {}
+func (Start) command()       {}
+func (Ready) command()       {}
+func (Leave) command()       {}
+func (Spectate) command()    {}
+func (BootUnready) command() {}
+func (Disconnect) command()  {}
+func (Resume) command()      {}
+func (GraceOver) command()   {}
+func (Replay) command()      {}
+
+const (
+	ReadyToGo = "ready to go"
+	ReadyForNextSetup = "ready for next setup"
+)
+
+// A Message is anything a Game can tell a player.
+type Message interface {
+	message()
+}
+
+// An Event is a Message addressed to a player by name.
+// Events with an empty To are meant for the hosting program itself (see ReadyTimer).
+type Event struct {
+	To string
+	Message Message
+}
+
+type Avatar struct {
+	Emoji string `json:"emoji"`
+	Name string `json:"name"`
+}
+
+type Initialized struct { // you've started a new game (and you're the seeker)
+	Code string `json:"code"`
+	Emoji string `json:"emoji"`
+	Name string `json:"name"`
+	Token string `json:"token"` // session token, for Resume
+}
+
+type Wait struct { // you've joined a game; wait for the start or for the next round
+	NextRound bool `json:"nextRound"`
+	Code string `json:"code"`
+	Emoji string `json:"emoji"`
+	Name string `json:"name"`
+	Token string `json:"token"` // session token, for Resume
+	Others []Avatar `json:"others"`
+}
+
+type Spectating struct { // you're watching
+	Code string `json:"code"`
+	Players []Avatar `json:"players"`
+}
+
+type Waitlisted struct { // the game's full; you're Position in line for a seat
+	Code string `json:"code"`
+	Position int `json:"position"`
+}
+
+type Joined struct {
+	Emoji string `json:"emoji"`
+	Name string `json:"name"`
+}
+
+type Left struct {
+	Emoji string `json:"emoji"`
+	Name string `json:"name"`
+	Hiding bool `json:"hiding"` // if true, Row and Col is where they were hiding
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+type SeekerLeft struct{} // before the first round: you are now seeker
+
+type NowHost struct{} // the host left, and you're the host now
+
+type TooFewHiders struct{}
+
+type Placement struct {
+	Emoji string `json:"emoji"`
+	Name string `json:"name"`
+	Row int `json:"row"`
+	Col int `json:"col"`
+	Score int `json:"score"`
+}
+
+type Setup struct {
+	Seeker string `json:"seeker"` // emoji
+	Forest Forest `json:"forest"` // see Terrain
+	Players []Placement `json:"players"`
+	Rules Ruleset `json:"rules"`
+	Seed int64 `json:"seed,string"` // Replay this to play the round again. (a string: it's too big for JavaScript's numbers)
+}
+
+type Go struct{}
+
+type Moved struct {
+	Emoji string `json:"emoji"`
+	FromRow int `json:"fromRow"`
+	FromCol int `json:"fromCol"`
+	ToRow int `json:"toRow"`
+	ToCol int `json:"toCol"`
+}
+
+type Found struct {
+	Emoji string `json:"emoji"`
+	Name string `json:"name"`
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+type InSight struct { // seeker only: the hiders you can see (see Ruleset.Sight)
+	Players []Placement `json:"players"`
+}
+
+type TreeRemoved struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+type Winner struct {
+	Emoji string `json:"emoji"`
+	Name string `json:"name"`
+}
+
+const ( // round over reasons
+	ReasonTwoPlayerGame = "2 player game"
+	ReasonTooFewHiders = "too few hiders"
+	ReasonSeekerLeft = "seeker left"
+)
+
+type RoundOver struct {
+	Reason string `json:"reason"`
+	NowSeeker bool `json:"nowSeeker"` // you've been appointed seeker
+	CantContinue bool `json:"cantContinue"` // too few hiders to start next round
+}
+
+type Boot struct{} // this player was booted; close their connection
+
+type ReadyTimer struct { // after After, send BootUnready{Stage, Token}
+	Stage string
+	Token int
+	After time.Duration
+}
+
+type GraceTimer struct { // after After, send GraceOver{Name, Away}
+	Name string
+	Away int
+	After time.Duration
+}
+
+func (Initialized) message()  {}
+func (Wait) message()         {}
+func (Spectating) message()   {}
+func (Waitlisted) message()   {}
+func (Joined) message()       {}
+func (Left) message()         {}
+func (SeekerLeft) message()   {}
+func (NowHost) message()      {}
+func (TooFewHiders) message() {}
+func (Setup) message()        {}
+func (Go) message()           {}
+func (Moved) message()        {}
+func (Found) message()        {}
+func (InSight) message()      {}
+func (TreeRemoved) message()  {}
+func (Winner) message()       {}
+func (RoundOver) message()    {}
+func (Boot) message()         {}
+func (ReadyTimer) message()   {}
+func (GraceTimer) message()   {}
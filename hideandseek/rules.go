@@ -0,0 +1,113 @@
+package hideandseek
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A Ruleset is what the host picked when they made the game. Leave a field
+// zero and you get the default; Filled says what that works out to.
+// Every player gets the filled in rules with each Setup.
+type Ruleset struct {
+	TreesPerPlayer int `json:"treesPerPlayer,omitempty"` // forest density
+	Aspect float64 `json:"aspect,omitempty"` // forest height / width (see treesPerRow). 0: fit everyone's screens
+	MoveRadius int `json:"moveRadius,omitempty"` // how many steps a move can be
+	NoDiagonals bool `json:"noDiagonals,omitempty"` // steps are up/down/left/right only
+	ReadyTimeout int `json:"readyTimeout,omitempty"` // seconds before unready players get booted
+	MaxPlayers int `json:"maxPlayers,omitempty"` // joining a full game gets ErrGameFull...
+	Waitlist bool `json:"waitlist,omitempty"` // ...or a place in line for the next free seat
+	WinWhen string `json:"winWhen,omitempty"` // WinLastHider or WinAllFound
+	Scoring string `json:"scoring,omitempty"` // ScoreWinner or ScoreFinds
+	Theme string `json:"theme,omitempty"` // see themes.go. empty: whatever's triggered
+	Forest string `json:"forest,omitempty"` // which ForestGenerator (see generators.go). empty: the theme's, or DefaultForest
+	Seed int64 `json:"seed,string,omitempty"` // non-zero: the same rounds every game (see seeds.go). a string in JSON
+	Terrain bool `json:"terrain,omitempty"` // mix thickets, rocks and rivers into the forest (see terrain.go)
+	Sight int `json:"sight,omitempty"` // how far the seeker can see hiders (0: not at all)
+}
+
+// when a round with more than one hider is over (2 player rounds are always over when the hider is found)
+const (
+	WinLastHider = "last-hider" // one hider left: they win
+	WinAllFound = "all-found" // play until everyone's found: the last one found wins
+)
+
+const (
+	ScoreWinner = "winner" // a point for winning a round
+	ScoreFinds = "finds" // that, and the seeker gets a point for every hider they find
+)
+
+const (
+	maxTreesPerPlayer = 50
+	minAspect, maxAspect = 0.25, 4.0
+	defaultAspect = 2 // phones are about 1:2
+	maxMoveRadius = 5
+	maxSight = 10
+	minReadyTimeout, maxReadyTimeout = 3, 120
+)
+
+func DefaultRules() Ruleset {
+	return Ruleset{
+		TreesPerPlayer: 5,
+		MoveRadius: 1,
+		ReadyTimeout: int(ReadyTimeout / time.Second),
+		MaxPlayers: maxPlayersPerGame,
+		WinWhen: WinLastHider,
+		Scoring: ScoreWinner,
+	}
+}
+
+// Filled is r with the defaults put in wherever r doesn't say.
+func (r Ruleset) Filled() Ruleset {
+	d := DefaultRules()
+	if r.TreesPerPlayer == 0 { r.TreesPerPlayer = d.TreesPerPlayer }
+	if r.MoveRadius == 0 { r.MoveRadius = d.MoveRadius }
+	if r.ReadyTimeout == 0 { r.ReadyTimeout = d.ReadyTimeout }
+	if r.MaxPlayers == 0 { r.MaxPlayers = d.MaxPlayers }
+	if r.WinWhen == "" { r.WinWhen = d.WinWhen }
+	if r.Scoring == "" { r.Scoring = d.Scoring }
+	return r
+}
+
+// Validate says what's wrong with r, if anything. Zero fields are fine (they're defaults).
+func (r Ruleset) Validate() error {
+	r = r.Filled()
+	switch {
+	case r.TreesPerPlayer < 1 || r.TreesPerPlayer > maxTreesPerPlayer:
+		return fmt.Errorf("trees per player must be 1 to %d", maxTreesPerPlayer)
+	case r.Aspect != 0 && !(r.Aspect >= minAspect && r.Aspect <= maxAspect): // (NaN is neither)
+		return fmt.Errorf("aspect must be %g to %g", minAspect, maxAspect)
+	case r.MoveRadius < 1 || r.MoveRadius > maxMoveRadius:
+		return fmt.Errorf("move radius must be 1 to %d", maxMoveRadius)
+	case r.ReadyTimeout < minReadyTimeout || r.ReadyTimeout > maxReadyTimeout:
+		return fmt.Errorf("ready timeout must be %d to %d seconds", minReadyTimeout, maxReadyTimeout)
+	case r.Sight < 0 || r.Sight > maxSight:
+		return fmt.Errorf("sight must be 0 to %d", maxSight)
+	case r.MaxPlayers < 2 || r.MaxPlayers > maxPlayersPerGame:
+		return fmt.Errorf("max players must be 2 to %d", maxPlayersPerGame)
+	case r.WinWhen != WinLastHider && r.WinWhen != WinAllFound:
+		return fmt.Errorf("win when must be %s or %s", WinLastHider, WinAllFound)
+	case r.Scoring != ScoreWinner && r.Scoring != ScoreFinds:
+		return fmt.Errorf("scoring must be %s or %s", ScoreWinner, ScoreFinds)
+	}
+	if _, exists := findTheme(r.Theme); r.Theme != "" && !exists {
+		return fmt.Errorf("theme must be one of %s", strings.Join(ThemeNames(), ", "))
+	}
+	if _, exists := generators[r.Forest]; r.Forest != "" && !exists {
+		return fmt.Errorf("forest must be one of %s", strings.Join(ForestGenerators(), ", "))
+	}
+	return nil
+}
+
+func (r Ruleset) readyTimeout() time.Duration {
+	return time.Duration(r.ReadyTimeout) * time.Second
+}
+
+// inReach is whether a move from (fromRow, fromCol) to (row, col) is within the move radius.
+func (r Ruleset) inReach(fromRow, fromCol, row, col int) bool {
+	dr, dc := abs(row-fromRow), abs(col-fromCol)
+	if r.NoDiagonals {
+		return dr+dc <= r.MoveRadius
+	}
+	return dr <= r.MoveRadius && dc <= r.MoveRadius
+}
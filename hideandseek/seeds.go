@@ -0,0 +1,88 @@
+package hideandseek
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"sort"
+)
+
+// Seeds:
+// every game has its own random (for emojis and picking seekers), seeded
+// from Ruleset.Seed if the host gave one. Every round has its own too, for
+// the forest and where everyone starts, seeded by the game's random. The
+// round's seed goes out with Setup. The host can Replay a seed: the next
+// round is grown from it instead, so with the same players and rules they
+// get the same forest, and everyone starts in the same place.
+
+var (
+	ErrNotHost = errors.New("only the host can do that")
+	ErrNothingToReplay = errors.New("there's been no round to replay yet (give a seed)")
+)
+
+func newGameRandom(seed int64) (*rand.Rand, int64) {
+	if seed == 0 {
+		seed = random.Int63()
+	}
+	return rand.New(rand.NewSource(seed)), seed
+}
+
+// roundRandom is the random for a new round, and its seed
+func (g *Game) roundRandom() (*rand.Rand, int64) {
+	seed := g.random.Int63()
+	if g.replay != 0 {
+		seed, g.replay = g.replay, 0
+		log.Printf("\n%s: replaying round seed %d\n", g.code, seed)
+	}
+	return rand.New(rand.NewSource(seed)), seed
+}
+
+func (g *Game) replayRound(name string, seed int64) error {
+	if _, exists := g.players[name]; !exists {
+		return ErrNotInGame
+	}
+	if name != g.host {
+		return ErrNotHost
+	}
+	if seed == 0 {
+		seed = g.roundSeed // this round again
+	}
+	if seed == 0 {
+		return ErrNothingToReplay
+	}
+	g.replay = seed
+	return nil
+}
+
+// Host is who started the game (or took over when they left).
+func (g *Game) Host() string {
+	return g.host
+}
+
+// Seed is the game's seed (see Ruleset.Seed).
+func (g *Game) Seed() int64 {
+	return g.seed
+}
+
+// names is everyone's name in order, so the same seed always does the same thing
+func (g *Game) names() []string {
+	names := g.Players()
+	sort.Strings(names)
+	return names
+}
+
+// newHost is who takes over when the host leaves: the seeker, if they're a
+// person who's here, or else the first person who is. Never a bot.
+func (g *Game) newHost() {
+	g.host = ""
+	if len(g.players) == 0 {
+		return
+	}
+	here := func(p *player) bool { return !p.bot && p.away == 0 }
+	people := g.prefer(func(p *player) bool { return here(p) && p.seeker }, here, func(p *player) bool { return !p.bot })
+	if g.players[people[0]].bot { // nobody but bots
+		return
+	}
+	g.host = people[0]
+	g.send(g.host, NowHost{})
+}
@@ -0,0 +1,101 @@
+package hideandseek
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func setupIn(t *testing.T, events []Event) Setup {
+	t.Helper()
+	for _, e := range events {
+		if s, ok := e.Message.(Setup); ok {
+			return s
+		}
+	}
+	t.Fatalf("no setup in %v", events)
+	return Setup{}
+}
+
+func places(s Setup) map[string][2]int {
+	at := make(map[string][2]int)
+	for _, p := range s.Players {
+		at[p.Name] = [2]int{p.Row, p.Col}
+	}
+	return at
+}
+
+func TestReplay(t *testing.T) {
+	g, _ := New("TEST", Ruleset{Terrain: true})
+	join(t, g, "a", "b", "c", "d")
+	if _, err := g.Handle(Replay{Name: "a"}); err != ErrNothingToReplay {
+		t.Errorf("replaying this round before there's been one: %v", err)
+	}
+	first := setupIn(t, handle(t, g, Start{Name: "a"}))
+
+	if _, err := g.Handle(Replay{Name: "b"}); err != ErrNotHost {
+		t.Errorf("replay from someone who isn't host: %v", err)
+	}
+	handle(t, g, Replay{Name: "a"})
+	g.newSetup()
+	again := setupIn(t, g.out)
+	if again.Seed != first.Seed || !reflect.DeepEqual(again.Forest, first.Forest) || !reflect.DeepEqual(places(again), places(first)) {
+		t.Errorf("a replayed round should be the same as the first time")
+	}
+
+	g.out = nil
+	g.newSetup()
+	if next := setupIn(t, g.out); next.Seed == first.Seed {
+		t.Errorf("only the next round is replayed")
+	}
+}
+
+func TestGameSeed(t *testing.T) {
+	var setups []Setup
+	for _, code := range []string{"ONE", "TWO"} {
+		g, _ := New(code, Ruleset{Seed: 42, Forest: "groves"})
+		join(t, g, "z", "y", "x")
+		setups = append(setups, setupIn(t, handle(t, g, Start{Name: "z"})))
+	}
+	if setups[0].Seed != setups[1].Seed || !reflect.DeepEqual(setups[0].Forest, setups[1].Forest) || !reflect.DeepEqual(places(setups[0]), places(setups[1])) {
+		t.Errorf("two games with the same seed should have the same first round")
+	}
+}
+
+// the theme can change with the date, and a replay shouldn't
+func TestThemeDoesntChangeTheLayout(t *testing.T) {
+	layout := func(theme string) (string, map[string][2]int) {
+		g, _ := New("TEST", Ruleset{Seed: 7, Theme: theme, Terrain: true})
+		join(t, g, "a", "b", "c")
+		s := setupIn(t, handle(t, g, Start{Name: "a"}))
+		var b strings.Builder
+		for _, row := range s.Forest {
+			for _, cell := range row {
+				b.WriteString(TerrainOf(cell).Name + " ")
+			}
+			b.WriteString("\n")
+		}
+		return b.String(), places(s)
+	}
+	forest, forestPlaces := layout("forest")
+	santa, santaPlaces := layout("santa")
+	if forest != santa || !reflect.DeepEqual(forestPlaces, santaPlaces) {
+		t.Errorf("the same seed laid out differently for a different theme:\n%s\n%s", forest, santa)
+	}
+}
+
+func TestSeedIsAStringInJSON(t *testing.T) {
+	s := Setup{Forest: Forest{{'T'}}, Seed: 1<<62 + 1}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"seed":"4611686018427387905"`) {
+		t.Errorf("seed should be a string: %s", b)
+	}
+	var back Setup
+	if err := json.Unmarshal(b, &back); err != nil || back.Seed != s.Seed {
+		t.Errorf("seed didn't come back: %d, %v", back.Seed, err)
+	}
+}
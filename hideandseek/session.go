@@ -0,0 +1,76 @@
+package hideandseek
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"log"
+)
+
+// Dropped connections:
+// a player who disconnects keeps their seat for GracePeriod. While they're
+// away, nobody waits on them to be ready and they can't be booted. If they
+// Resume with their session token in time, they get their seat back (with a
+// fresh setup if a round is on). If not, they leave like anyone else.
+
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		log.Fatalf("CRASH: can't make a session token: %s", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func (g *Game) disconnect(name string) {
+	p, exists := g.players[name]
+	if !exists { return }
+
+	g.awayTokens++
+	p.away = g.awayTokens
+	log.Printf("\n%s/%s disconnected. holding their seat.\n", g.code, name)
+	g.send("", GraceTimer{Name: name, Away: p.away, After: GracePeriod})
+
+	for _, stage := range []string{ReadyToGo, ReadyForNextSetup} { // was anyone waiting on them?
+		if _, counting := g.pendingBoots[stage]; counting && everyonesReady(stage, g) {
+			delete(g.pendingBoots, stage)
+			g.everyoneIsReady(stage)
+		}
+	}
+}
+
+func (g *Game) resume(name, token string) error {
+	p, exists := g.players[name]
+	if !exists || p.token != token {
+		return ErrBadToken
+	}
+
+	p.away = 0
+	log.Printf("\n%s/%s is back.\n", g.code, name)
+
+	if p.seeker && !g.inRound && name == g.host {
+		g.send(name, Initialized{Code: g.code, Emoji: p.emoji, Name: name, Token: p.token})
+	} else {
+		g.send(name, g.wait(name))
+		if name == g.host { // Initialized is what says so, otherwise
+			g.send(name, NowHost{})
+		}
+		if p.seeker && !g.inRound { // the seeker's Start button
+			g.send(name, SeekerLeft{})
+		}
+	}
+
+	if g.roundLive && !p.waiting && !p.found {
+		g.send(name, g.setup())
+	} else if g.inRound {
+		p.ready[ReadyForNextSetup] = true // don't hold up the next round
+	}
+	return nil
+}
+
+func (g *Game) graceOver(name string, away int) {
+	p, exists := g.players[name]
+	if !exists || away == 0 || p.away != away {
+		return
+	}
+	log.Printf("\n%s/%s never came back.\n", g.code, name)
+	g.leave(name)
+}
@@ -0,0 +1,41 @@
+package hideandseek
+
+import "log"
+
+// Spectators watch a game without being in it: they don't get an emoji,
+// don't count toward the size of the forest, can't be seeker, and nobody
+// waits on them. They're told about the round the way a hider sees it.
+
+func (g *Game) spectate(name string) error {
+	if _, exists := g.players[name]; exists || g.spectators[name] || g.onWaitlist(name) {
+		return ErrNameTaken
+	}
+
+	g.spectators[name] = true
+	log.Printf("\nspectator has joined: %s/%s\n", g.code, name)
+
+	reply := Spectating{Code: g.code}
+	for n, p := range g.players {
+		reply.Players = append(reply.Players, Avatar{Emoji: p.emoji, Name: n})
+	}
+	g.send(name, reply)
+
+	if g.roundLive {
+		g.send(name, g.setup())
+		if g.going {
+			g.send(name, Go{})
+		}
+	}
+	return nil
+}
+
+func (g *Game) stopSpectating(name string) {
+	delete(g.spectators, name)
+	log.Printf("\nspectator left: %s/%s\n", g.code, name)
+}
+
+func (g *Game) showSpectators(m Message) {
+	for n := range g.spectators {
+		g.send(n, m)
+	}
+}
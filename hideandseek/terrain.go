@@ -0,0 +1,124 @@
+package hideandseek
+
+import "math/rand"
+
+// Every cell in a Forest is some kind of Terrain. Anything that isn't one
+// of the runes below is a tree (whatever the theme's trees look like).
+type Terrain struct {
+	Name string
+	Room int // how many hiders fit (0: nobody can hide here)
+	Cross bool // you can move onto it, and through it on a long move
+	BlocksView bool // the seeker can't see past it (see Ruleset.Sight)
+}
+
+const (
+	OpenGround = ' '
+	Wall = '🧱'
+	Thicket = '🌿'
+	River = '🌊'
+	Rock = '🪨'
+)
+
+var tree = Terrain{Name: "tree", Room: 1, Cross: true}
+
+var terrains = map[rune]Terrain{
+	OpenGround: {Name: "open ground", Cross: true},
+	Wall: {Name: "wall", BlocksView: true},
+	Thicket: {Name: "thicket", Room: 1, Cross: true, BlocksView: true}, // too dense to see through
+	River: {Name: "river"},
+	Rock: {Name: "rock", Cross: true, BlocksView: true}, // nowhere to hide, but the seeker can climb over
+}
+
+func TerrainOf(cell rune) Terrain {
+	if t, exists := terrains[cell]; exists {
+		return t
+	}
+	return tree
+}
+
+// Hideable is whether a hider can stop on cell.
+func Hideable(cell rune) bool {
+	return TerrainOf(cell).Room > 0
+}
+
+// placeTerrain mixes thickets, rocks and a river into a generated forest
+// (see Ruleset.Terrain). It never takes away somewhere to hide.
+func placeTerrain(f Forest, random *rand.Rand) Forest {
+	for r := range f {
+		for c := range f[r] {
+			switch {
+			case TerrainOf(f[r][c]) == tree && random.Intn(5) == 0:
+				f[r][c] = Thicket
+			case f[r][c] == OpenGround && random.Intn(3) == 0:
+				f[r][c] = Rock
+			}
+		}
+	}
+
+	if len(f) < 6 || hasWalls(f) { // too small to split, or indoors
+		return f
+	}
+	// a river across the middle somewhere, on a row of its own, with a ford or two for the seeker
+	at := 2 + random.Intn(len(f)-4)
+	river := make([]rune, len(f[0]))
+	for c := range river {
+		river[c] = River
+	}
+	for fords := 1 + len(river)/6; fords > 0; fords-- {
+		river[random.Intn(len(river))] = OpenGround
+	}
+	f = append(f[:at:at], append(Forest{river}, f[at:]...)...)
+	return f
+}
+
+func hasWalls(f Forest) bool {
+	for r := range f {
+		for c := range f[r] {
+			if f[r][c] == Wall { return true }
+		}
+	}
+	return false
+}
+
+// inSight is whether the seeker at (fromRow, fromCol) can see (row, col):
+// it's close enough, and nothing that BlocksView is in between.
+func (g *Game) inSight(fromRow, fromCol, row, col int) bool {
+	if abs(row-fromRow) > g.rules.Sight || abs(col-fromCol) > g.rules.Sight {
+		return false
+	}
+	steps := abs(row-fromRow)
+	if abs(col-fromCol) > steps { steps = abs(col-fromCol) }
+	for i := 1; i < steps; i++ { // every cell on the line between, not counting the ends
+		r := fromRow + roundDiv((row-fromRow)*i, steps)
+		c := fromCol + roundDiv((col-fromCol)*i, steps)
+		if TerrainOf(g.wood[r][c]).BlocksView {
+			return false
+		}
+	}
+	return true
+}
+
+func roundDiv(a, b int) int { // a/b, rounded to the nearest whole number
+	if a < 0 {
+		return -((-a*2 + b) / (b*2))
+	}
+	return (a*2 + b) / (b*2)
+}
+
+// lookAround tells the seeker which hiders they can see (see Ruleset.Sight)
+func (g *Game) lookAround() {
+	if g.rules.Sight == 0 || !g.roundLive {
+		return
+	}
+	for n, s := range g.players {
+		if !s.seeker || s.row < 0 { continue }
+		seen := InSight{}
+		for m, p := range g.players {
+			if p.seeker || p.found || p.waiting || p.row < 0 { continue }
+			if g.inSight(s.row, s.col, p.row, p.col) {
+				seen.Players = append(seen.Players, Placement{Emoji: p.emoji, Name: m, Row: p.row, Col: p.col, Score: p.score})
+			}
+		}
+		g.send(n, seen)
+	}
+}
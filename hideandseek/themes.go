@@ -0,0 +1,160 @@
+package hideandseek
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// A Theme dresses up the forest: what the trees look like, and special
+// avatars for players with certain names. The host can pick one (see
+// Ruleset.Theme). If they don't, each round gets the first theme in the
+// registry that's triggered by someone's name or by today's date, and
+// DefaultTheme if none are.
+type Theme struct {
+	Name string
+	Trees []rune // picked at random for each tree
+	Avatars []SpecialAvatar
+	Forest string // the ForestGenerator that goes with it, unless the host picked one
+
+	// triggers (all optional)
+	NameIs []string // lower case: a player with exactly this name
+	NameHas []string // lower case: a player whose name has this in it
+	Dates []DateRange
+}
+
+type SpecialAvatar struct {
+	Emoji string
+	Names []string // lower case: the first player with one of these names gets Emoji
+}
+
+// A DateRange is From to To inclusive, every year. It can wrap past New Year's.
+type DateRange struct {
+	From, To MonthDay
+}
+
+type MonthDay struct {
+	Month time.Month
+	Day int
+}
+
+const DefaultTheme = "forest"
+
+var ErrThemeExists = errors.New("there's already a theme with that name")
+
+var santaNames = []string{"santa", "santa claus", "father christmas", "father xmas", "saint nicholas", "st. nicholas", "saint nick", "st. nick", "kris kringle", "kringle"}
+
+var themes = []Theme{ // earlier themes trump later ones
+	{Name: DefaultTheme, Trees: []rune("🌲🌳")},
+	{
+		Name: "santa",
+		Trees: []rune{'🎄'},
+		Avatars: []SpecialAvatar{{Emoji: "🎅", Names: santaNames}},
+		NameIs: santaNames,
+		Dates: []DateRange{{From: MonthDay{time.December, 24}, To: MonthDay{time.December, 26}}},
+	},
+	{Name: "indoor", Trees: []rune{'🚪'}, Forest: "indoor", NameHas: []string{"indoor", "inside"}},
+	{Name: "desert", Trees: []rune("🌴🌵")}, // only if the host asks
+}
+
+// RegisterTheme adds t to the themes hosts can pick from (and that can
+// trigger on their own). Call it before any games are made.
+func RegisterTheme(t Theme) error {
+	if _, exists := findTheme(t.Name); exists {
+		return ErrThemeExists
+	}
+	if t.Name == "" || len(t.Trees) == 0 {
+		return errors.New("a theme needs a name and some trees")
+	}
+	themes = append(themes, t)
+	return nil
+}
+
+// ThemeNames lists every registered theme.
+func ThemeNames() []string {
+	names := make([]string, len(themes))
+	for i, t := range themes {
+		names[i] = t.Name
+	}
+	return names
+}
+
+func findTheme(name string) (Theme, bool) {
+	for _, t := range themes {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Theme{}, false
+}
+
+// theme is what this round looks like
+func (g *Game) theme() Theme {
+	if t, picked := findTheme(g.rules.Theme); picked {
+		return t
+	}
+	names := make([]string, 0, len(g.players))
+	for n := range g.players {
+		names = append(names, strings.ToLower(n))
+	}
+	now := time.Now()
+	for _, t := range themes {
+		if t.triggeredBy(names, now) {
+			return t
+		}
+	}
+	t, _ := findTheme(DefaultTheme)
+	return t
+}
+
+func (t Theme) triggeredBy(names []string, now time.Time) bool {
+	for _, name := range names {
+		for _, is := range t.NameIs {
+			if name == is { return true }
+		}
+		for _, has := range t.NameHas {
+			if strings.Contains(name, has) { return true }
+		}
+	}
+	for _, d := range t.Dates {
+		if d.contains(now) { return true }
+	}
+	return false
+}
+
+func (d DateRange) contains(t time.Time) bool {
+	day := MonthDay{t.Month(), t.Day()}
+	if !d.To.before(d.From) {
+		return !day.before(d.From) && !d.To.before(day)
+	}
+	return !day.before(d.From) || !d.To.before(day) // e.g. Dec 31 to Jan 1
+}
+
+func (a MonthDay) before(b MonthDay) bool {
+	return a.Month < b.Month || (a.Month == b.Month && a.Day < b.Day)
+}
+
+// specialAvatar is the emoji a theme has for name, if there is one and
+// nobody has it yet. Only the host's theme counts if they picked one.
+func specialAvatar(g *Game, name string) (string, bool) {
+	name = strings.ToLower(name)
+	for _, t := range themes {
+		if g.rules.Theme != "" && t.Name != g.rules.Theme { continue }
+		for _, a := range t.Avatars {
+			if g.specialInUse[a.Emoji] { continue }
+			for _, n := range a.Names {
+				if n == name { return a.Emoji, true }
+			}
+		}
+	}
+	return "", false
+}
+
+func isSpecialAvatar(emoji string) bool {
+	for _, t := range themes {
+		for _, a := range t.Avatars {
+			if a.Emoji == emoji { return true }
+		}
+	}
+	return false
+}
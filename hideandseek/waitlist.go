@@ -0,0 +1,65 @@
+package hideandseek
+
+import "log"
+
+// A game holds at most Ruleset.MaxPlayers. Anyone who joins a full game is
+// turned away with ErrGameFull, unless the host turned on Ruleset.Waitlist:
+// then they queue up, and whoever's first in line gets the next free seat
+// (and joins just as if the game had had room all along).
+
+type waiter struct {
+	name string
+	emoji string // the one they asked for
+	aspect float64
+	bot bool
+}
+
+// Full is whether the next player to join would be turned away (or queued).
+func (g *Game) Full() bool {
+	return len(g.players) >= g.rules.MaxPlayers
+}
+
+func (g *Game) onWaitlist(name string) bool {
+	for _, w := range g.waitlist {
+		if w.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) joinWaitlist(name, wantEmoji string, aspect float64, bot bool) {
+	g.waitlist = append(g.waitlist, waiter{name: name, emoji: wantEmoji, aspect: aspect, bot: bot})
+	log.Printf("\n%s/%s is waitlisted (%d in line)\n", g.code, name, len(g.waitlist))
+	g.send(name, Waitlisted{Code: g.code, Position: len(g.waitlist)})
+}
+
+func (g *Game) leaveWaitlist(name string) {
+	for i, w := range g.waitlist {
+		if w.name != name { continue }
+		g.waitlist = append(g.waitlist[:i:i], g.waitlist[i+1:]...)
+		for j := i; j < len(g.waitlist); j++ { // everyone behind them moves up
+			g.send(g.waitlist[j].name, Waitlisted{Code: g.code, Position: j+1})
+		}
+		return
+	}
+}
+
+// admitWaiting gives free seats to whoever's been waiting longest
+func (g *Game) admitWaiting() {
+	admitted := 0
+	for len(g.waitlist) > 0 && !g.Full() {
+		w := g.waitlist[0]
+		g.waitlist = g.waitlist[1:]
+		if err := g.join(w.name, w.emoji, w.aspect, w.bot); err != nil { // their name was taken while they waited
+			g.send(w.name, Boot{})
+			continue
+		}
+		admitted++
+	}
+	if admitted > 0 {
+		for i, w := range g.waitlist {
+			g.send(w.name, Waitlisted{Code: g.code, Position: i+1})
+		}
+	}
+}
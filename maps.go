@@ -0,0 +1,86 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/edmangimelli/hide-and-seek/hideandseek"
+)
+
+// Hosts upload maps (see hideandseek.Map) with a POST to /maps, and get back
+// an id to put in their new game message. Maps are kept in memory; once
+// there are -maxmaps of them, the oldest go.
+
+var maxMaps = flag.Int("maxmaps", 1000, "how many uploaded maps to keep")
+
+const maxMapBytes = 64 << 10
+
+var ErrNoSuchMap = errors.New("no such map (it might have been a while since it was uploaded)")
+
+var uploadedMaps = make(map[string]hideandseek.Map)
+var mapOrder []string // oldest first
+var mapsMutex = sync.Mutex{}
+
+type uploaded struct {
+	ID string `json:"id"`
+	Rows int `json:"rows"`
+	Cols int `json:"cols"`
+	Spawns int `json:"spawns"`
+}
+
+func uploadMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "POST a map to upload it", http.StatusMethodNotAllowed)
+		return
+	}
+	text, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxMapBytes))
+	if err != nil {
+		http.Error(w, "map is too big", http.StatusRequestEntityTooLarge)
+		return
+	}
+	m, err := hideandseek.ParseMap(string(text))
+	if err == nil {
+		err = m.Validate(2)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := newMapID()
+	mapsMutex.Lock()
+	uploadedMaps[id] = m
+	mapOrder = append(mapOrder, id)
+	for len(mapOrder) > *maxMaps {
+		delete(uploadedMaps, mapOrder[0])
+		mapOrder = mapOrder[1:]
+	}
+	mapsMutex.Unlock()
+	log.Printf("\nmap uploaded: %s (%dx%d)\n", id, len(m.Forest), len(m.Forest[0]))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(uploaded{ID: id, Rows: len(m.Forest), Cols: len(m.Forest[0]), Spawns: len(m.Spawns)})
+}
+
+func lookupMap(id string) (hideandseek.Map, bool) {
+	mapsMutex.Lock()
+	defer mapsMutex.Unlock()
+	m, exists := uploadedMaps[id]
+	return m, exists
+}
+
+func newMapID() string {
+	b := make([]byte, 6)
+	if _, err := crand.Read(b); err != nil {
+		log.Fatalf("CRASH: can't make a map id: %s", err)
+	}
+	return hex.EncodeToString(b)
+}
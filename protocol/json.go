@@ -0,0 +1,49 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+)
+
+type envelope struct {
+	Type string `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(m interface{}) []string {
+	name, known := names[reflect.TypeOf(m)]
+	if !known {
+		log.Printf("\nBUG: don't know how to encode %#v\n", m)
+		return nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		log.Printf("\nBUG: couldn't encode %#v: %s\n", m, err)
+		return nil
+	}
+	frame, _ := json.Marshal(envelope{Type: name, Data: data})
+	return []string{string(frame)}
+}
+
+func (jsonCodec) Decode(frame []byte) (interface{}, error) {
+	var e envelope
+	if err := json.Unmarshal(frame, &e); err != nil {
+		return nil, err
+	}
+	v, known := types[e.Type]
+	if !known {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownMessage, e.Type)
+	}
+
+	m := reflect.New(reflect.TypeOf(v))
+	if len(e.Data) > 0 {
+		if err := json.Unmarshal(e.Data, m.Interface()); err != nil {
+			return nil, err
+		}
+	}
+	return m.Elem().Interface(), nil
+}
@@ -0,0 +1,284 @@
+package protocol
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/edmangimelli/hide-and-seek/hideandseek"
+)
+
+// legacy is the newline separated strings client.html speaks.
+type legacy struct{}
+
+func (legacy) Decode(frame []byte) (interface{}, error) {
+	msg := strings.Split(string(frame), "\n")
+
+	// fields makes sure the message has at least n lines after the first
+	fields := func(n int) error {
+		if len(msg) < n+1 {
+			return ErrMissingFields
+		}
+		return nil
+	}
+	rowCol := func() (int, int, error) {
+		if err := fields(2); err != nil {
+			return 0, 0, err
+		}
+		row, rowErr := strconv.Atoi(msg[1])
+		col, colErr := strconv.Atoi(msg[2])
+		if rowErr != nil || colErr != nil {
+			return 0, 0, ErrNotANumber
+		}
+		return row, col, nil
+	}
+
+	switch msg[0] {
+	case "add bot": // (difficulty)
+		if len(msg) > 1 {
+			return AddBot{Difficulty: msg[1]}, nil
+		}
+		return AddBot{}, nil
+
+	case "good bye":
+		return GoodBye{}, nil
+
+	case "join": // code // name // (emoji) // (aspect)
+		if err := fields(2); err != nil {
+			return nil, err
+		}
+		m := Join{Code: msg[1], Name: msg[2]}
+		if len(msg) > 3 {
+			m.Emoji = msg[3]
+		}
+		if len(msg) > 4 {
+			aspect, err := parseAspect(msg[4])
+			if err != nil {
+				return nil, err
+			}
+			m.Aspect = aspect
+		}
+		return m, nil
+
+	case "move to": // row // col
+		row, col, err := rowCol()
+		if err != nil {
+			return nil, err
+		}
+		return MoveTo{Row: row, Col: col}, nil
+
+	case "new game": // name // (rules) // (emoji) // (map) // (aspect)
+		if err := fields(1); err != nil {
+			return nil, err
+		}
+		m := NewGame{Name: msg[1]}
+		if len(msg) > 2 { // an empty line is the default rules
+			rules, err := decodeRules(msg[2])
+			if err != nil {
+				return nil, err
+			}
+			m.Rules = rules
+		}
+		if len(msg) > 3 {
+			m.Emoji = msg[3]
+		}
+		if len(msg) > 4 {
+			m.Map = msg[4]
+		}
+		if len(msg) > 5 {
+			aspect, err := parseAspect(msg[5])
+			if err != nil {
+				return nil, err
+			}
+			m.Aspect = aspect
+		}
+		return m, nil
+
+	case "ready to go", "ready for next setup":
+		return Ready{Stage: msg[0]}, nil
+
+	case "replay": // (seed)
+		if len(msg) < 2 || msg[1] == "" {
+			return Replay{}, nil
+		}
+		seed, err := strconv.ParseInt(msg[1], 10, 64)
+		if err != nil {
+			return nil, ErrNotANumber
+		}
+		return Replay{Seed: seed}, nil
+
+	case "resume": // code // name // token
+		if err := fields(3); err != nil {
+			return nil, err
+		}
+		return Resume{Code: msg[1], Name: msg[2], Token: msg[3]}, nil
+
+	case "remove tree": // row // col
+		row, col, err := rowCol()
+		if err != nil {
+			return nil, err
+		}
+		return RemoveTree{Row: row, Col: col}, nil
+
+	case "spectate": // code // (name)
+		if err := fields(1); err != nil {
+			return nil, err
+		}
+		if len(msg) > 2 {
+			return Spectate{Code: msg[1], Name: msg[2]}, nil
+		}
+		return Spectate{Code: msg[1]}, nil
+
+	case "start":
+		return Start{}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownMessage, msg[0])
+}
+
+func parseAspect(s string) (float64, error) {
+	if s == "" { // they didn't say
+		return 0, nil
+	}
+	aspect, err := parseFinite(s)
+	if err != nil {
+		return 0, ErrNotANumber
+	}
+	return aspect, nil
+}
+
+// Encode keeps every field where client.html has always found it. What's
+// been added since goes on the end, or if the message ends in a list, in a
+// frame of its own (which old clients don't know, so they skip it).
+func (legacy) Encode(m interface{}) []string {
+	frame := encode(m)
+	if frame == "" {
+		return nil
+	}
+	switch m := m.(type) {
+	case hideandseek.Setup: // first, so the rules are in before the round starts
+		return []string{fmt.Sprintf("rules\n%s\nseed\n%d", encodeRules(m.Rules), m.Seed), frame}
+	case hideandseek.Wait:
+		return []string{frame, "token\n" + m.Token}
+	}
+	return []string{frame}
+}
+
+func encode(m interface{}) string {
+	switch m := m.(type) {
+	case Bye:
+		return "bye!"
+
+	case NoSuchGame:
+		if m.DidYouMean != "" {
+			return fmt.Sprintf("no such game\n%s\n%s", m.Code, m.DidYouMean)
+		}
+		return fmt.Sprintf("no such game\n%s", m.Code)
+
+	case NameTaken:
+		return fmt.Sprintf("name is taken\n%s", m.Name)
+
+	case GameFull:
+		return fmt.Sprintf("game full\n%s", m.Code)
+
+	case TooManyGames:
+		return "too many games in session"
+
+	case CantResume:
+		return "can't resume"
+
+	case Error:
+		return fmt.Sprintf("error\n%s", m.Reason)
+
+	case hideandseek.Initialized:
+		return fmt.Sprintf("game initialized\n%s\n%s\n%s\n%s", m.Code, m.Emoji, m.Name, m.Token)
+
+	case hideandseek.Wait:
+		var msg string
+		if m.NextRound {
+			msg = "wait for next round"
+		} else {
+			msg = "wait for start"
+		}
+		msg += fmt.Sprintf("\n%s\n%s\n%s", m.Code, m.Emoji, m.Name)
+		for _, o := range m.Others {
+			msg += fmt.Sprintf("\n%s\n%s", o.Emoji, o.Name)
+		}
+		return msg
+
+	case hideandseek.Waitlisted:
+		return fmt.Sprintf("waitlisted\n%s\n%d", m.Code, m.Position)
+
+	case hideandseek.Spectating:
+		msg := fmt.Sprintf("spectating\n%s", m.Code)
+		for _, p := range m.Players {
+			msg += fmt.Sprintf("\n%s\n%s", p.Emoji, p.Name)
+		}
+		return msg
+
+	case hideandseek.Joined:
+		return fmt.Sprintf("joined\n%s\n%s", m.Emoji, m.Name)
+
+	case hideandseek.Left:
+		if m.Hiding {
+			return fmt.Sprintf("left\n%s\n%s\n%d\n%d", m.Emoji, m.Name, m.Row, m.Col)
+		}
+		return fmt.Sprintf("left\n%s\n%s", m.Emoji, m.Name)
+
+	case hideandseek.SeekerLeft:
+		return "seeker left\nyou are now seeker"
+
+	case hideandseek.NowHost:
+		return "now host"
+
+	case hideandseek.TooFewHiders:
+		return "too few hiders"
+
+	case hideandseek.Setup:
+		msg := fmt.Sprintf("setup\nseeker %s", m.Seeker)
+		msg += fmt.Sprintf("\nforest\n%d\n", len(m.Forest[0]))
+		for _, treeLine := range m.Forest {
+			msg += string(treeLine)
+		}
+		for _, p := range m.Players {
+			msg += fmt.Sprintf("\n%s\n%s\n%d\n%d\n%d", p.Emoji, p.Name, p.Row, p.Col, p.Score)
+		}
+		return msg
+
+	case hideandseek.Go:
+		return "go!"
+
+	case hideandseek.Moved:
+		return fmt.Sprintf("moved\n%s\nfrom\n%d\n%d\nto\n%d\n%d", m.Emoji, m.FromRow, m.FromCol, m.ToRow, m.ToCol)
+
+	case hideandseek.Found:
+		return fmt.Sprintf("found\n%s\n%s\n%d\n%d", m.Emoji, m.Name, m.Row, m.Col)
+
+	case hideandseek.InSight:
+		msg := "in sight"
+		for _, p := range m.Players {
+			msg += fmt.Sprintf("\n%s\n%s\n%d\n%d", p.Emoji, p.Name, p.Row, p.Col)
+		}
+		return msg
+
+	case hideandseek.TreeRemoved:
+		return fmt.Sprintf("remove tree\n%d\n%d", m.Row, m.Col)
+
+	case hideandseek.Winner:
+		return fmt.Sprintf("winner\n%s\n%s", m.Emoji, m.Name)
+
+	case hideandseek.RoundOver:
+		msg := "round over\n" + m.Reason
+		if m.NowSeeker {
+			msg += "\nyou are now seeker"
+		}
+		if m.CantContinue {
+			msg += "\ntoo few hiders to start next round"
+		}
+		return msg
+	}
+
+	log.Printf("\nBUG: don't know how to encode %#v\n", m)
+	return ""
+}
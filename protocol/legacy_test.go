@@ -0,0 +1,35 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/edmangimelli/hide-and-seek/hideandseek"
+)
+
+// old clients find everything where client.html always has
+func TestLegacyPositions(t *testing.T) {
+	tests := []struct {
+		name string
+		m interface{}
+		want []string
+	}{
+		{"initialized", hideandseek.Initialized{Code: "AB12", Emoji: "🦊", Name: "fox", Token: "t0k"},
+			[]string{"game initialized\nAB12\n🦊\nfox\nt0k"}},
+		{"wait for start", hideandseek.Wait{Code: "AB12", Emoji: "🦊", Name: "fox", Token: "t0k",
+			Others: []hideandseek.Avatar{{Emoji: "🐻", Name: "bear"}}},
+			[]string{"wait for start\nAB12\n🦊\nfox\n🐻\nbear", "token\nt0k"}},
+		{"wait for next round", hideandseek.Wait{NextRound: true, Code: "AB12", Emoji: "🦊", Name: "fox", Token: "t0k"},
+			[]string{"wait for next round\nAB12\n🦊\nfox", "token\nt0k"}},
+		{"setup", hideandseek.Setup{Seeker: "🦊", Forest: hideandseek.Forest{[]rune("🌲🌲"), []rune("🌲 ")}, Seed: 42,
+			Rules: hideandseek.Ruleset{MoveRadius: 1},
+			Players: []hideandseek.Placement{{Emoji: "🦊", Name: "fox", Row: 0, Col: 1, Score: 2}}},
+			[]string{"rules\n" + encodeRules(hideandseek.Ruleset{MoveRadius: 1}) + "\nseed\n42",
+				"setup\nseeker 🦊\nforest\n2\n🌲🌲🌲 \n🦊\nfox\n0\n1\n2"}},
+	}
+	for _, test := range tests {
+		if got := Legacy.Encode(test.m); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s:\n got %q\nwant %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package protocol
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/edmangimelli/hide-and-seek/hideandseek"
+)
+
+const maxNameLength = 40
+
+var (
+	ErrNotInbound = errors.New("that's a message from the server, not to it")
+	ErrBadName = errors.New("names can't be empty, can't have line breaks and can be at most 40 characters")
+	ErrNoCode = errors.New("which game? (no code)")
+	ErrNoToken = errors.New("session token is missing")
+	ErrNotInAGame = errors.New("join or start a game first")
+	ErrAlreadyInAGame = errors.New("you're already in a game")
+)
+
+// Parse decodes a frame from a client and makes sure it makes sense:
+// it's something clients can send, its fields are filled in properly, and
+// it fits where the client is (in a game or not). Anything that gets past
+// Parse is safe to act on.
+func Parse(codec Codec, frame []byte, inGame bool) (interface{}, error) {
+	msg, err := codec.Decode(frame)
+	if err != nil {
+		return nil, err
+	}
+	if err := check(msg); err != nil {
+		return nil, err
+	}
+
+	switch msg.(type) {
+	case GoodBye:
+	case NewGame, Join, Spectate, Resume:
+		if inGame {
+			return nil, ErrAlreadyInAGame
+		}
+	default: // everything else is done in a game
+		if !inGame {
+			return nil, ErrNotInAGame
+		}
+	}
+	return msg, nil
+}
+
+func check(msg interface{}) error {
+	switch m := msg.(type) {
+	case GoodBye, MoveTo, Start, AddBot, RemoveTree, Replay:
+		return nil
+	case NewGame:
+		return checkName(m.Name)
+	case Join:
+		if m.Code == "" {
+			return ErrNoCode
+		}
+		return checkName(m.Name)
+	case Spectate:
+		if m.Code == "" {
+			return ErrNoCode
+		}
+		if m.Name == "" { // they don't have to give one
+			return nil
+		}
+		return checkName(m.Name)
+	case Resume:
+		if m.Code == "" {
+			return ErrNoCode
+		}
+		if m.Token == "" {
+			return ErrNoToken
+		}
+		return checkName(m.Name)
+	case Ready:
+		if m.Stage != hideandseek.ReadyToGo && m.Stage != hideandseek.ReadyForNextSetup {
+			return hideandseek.ErrUnknownStage
+		}
+		return nil
+	}
+	return ErrNotInbound
+}
+
+func checkName(name string) error {
+	if name == "" || strings.ContainsAny(name, "\r\n") || len([]rune(name)) > maxNameLength {
+		return ErrBadName
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package protocol
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/edmangimelli/hide-and-seek/hideandseek"
+)
+
+var errAny = errors.New("any error") // for errors from encoding/json, which has no sentinels
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		codec Codec
+		frame string
+		inGame bool
+		want interface{}
+		err error
+	}{
+		{"legacy move", Legacy, "move to\n1\n2", true, MoveTo{Row: 1, Col: 2}, nil},
+		{"json move", JSON, `{"type":"moveTo","data":{"row":1,"col":2}}`, true, MoveTo{Row: 1, Col: 2}, nil},
+		{"legacy join", Legacy, "join\nAB12\nfox\n🦊\n1.5", false, Join{Code: "AB12", Name: "fox", Emoji: "🦊", Aspect: 1.5}, nil},
+		{"json join", JSON, `{"type":"join","data":{"code":"AB12","name":"fox"}}`, false, Join{Code: "AB12", Name: "fox"}, nil},
+		{"legacy replay", Legacy, "replay\n42", true, Replay{Seed: 42}, nil},
+		{"json replay", JSON, `{"type":"replay","data":{"seed":"42"}}`, true, Replay{Seed: 42}, nil},
+		{"legacy ready", Legacy, "ready to go", true, Ready{Stage: hideandseek.ReadyToGo}, nil},
+		{"json no data", JSON, `{"type":"start"}`, true, Start{}, nil},
+
+		{"legacy empty", Legacy, "", false, nil, ErrUnknownMessage},
+		{"json empty", JSON, "", false, nil, errAny},
+		{"json not json", JSON, "move to\n1\n2", true, nil, errAny},
+
+		{"legacy join, no name", Legacy, "join\nAB12", false, nil, ErrMissingFields},
+		{"legacy move, no col", Legacy, "move to\n1", true, nil, ErrMissingFields},
+		{"legacy resume, no token", Legacy, "resume\nAB12\nfox", false, nil, ErrMissingFields},
+		{"legacy new game, no name", Legacy, "new game", false, nil, ErrMissingFields},
+		{"legacy empty name", Legacy, "new game\n", false, nil, ErrBadName},
+		{"legacy join, no code", Legacy, "join\n\nfox", false, nil, ErrNoCode},
+		{"json join, no name", JSON, `{"type":"join","data":{"code":"AB12"}}`, false, nil, ErrBadName},
+		{"json join, no code", JSON, `{"type":"join","data":{"name":"fox"}}`, false, nil, ErrNoCode},
+		{"json resume, no token", JSON, `{"type":"resume","data":{"code":"AB12","name":"fox"}}`, false, nil, ErrNoToken},
+		{"json spectate, no code", JSON, `{"type":"spectate"}`, false, nil, ErrNoCode},
+		{"json name too long", JSON, `{"type":"newGame","data":{"name":"` + strings.Repeat("x", maxNameLength+1) + `"}}`, false, nil, ErrBadName},
+		{"json name with a line break", JSON, `{"type":"newGame","data":{"name":"f\nox"}}`, false, nil, ErrBadName},
+
+		{"legacy row isn't a number", Legacy, "move to\nx\n2", true, nil, ErrNotANumber},
+		{"legacy col isn't a number", Legacy, "remove tree\n1\n2.5", true, nil, ErrNotANumber},
+		{"legacy aspect isn't a number", Legacy, "join\nAB12\nfox\n🦊\ntall", false, nil, ErrNotANumber},
+		{"legacy aspect isn't finite", Legacy, "join\nAB12\nfox\n🦊\nNaN", false, nil, ErrNotANumber},
+		{"legacy seed isn't a number", Legacy, "replay\nabc", true, nil, ErrNotANumber},
+		{"json row isn't a number", JSON, `{"type":"moveTo","data":{"row":"1","col":2}}`, true, nil, errAny},
+		{"json seed isn't a number", JSON, `{"type":"replay","data":{"seed":"abc"}}`, true, nil, errAny},
+
+		{"json bad stage", JSON, `{"type":"ready","data":{"stage":"ready to party"}}`, true, nil, hideandseek.ErrUnknownStage},
+		{"json no stage", JSON, `{"type":"ready"}`, true, nil, hideandseek.ErrUnknownStage},
+		{"legacy bad stage", Legacy, "ready to party", true, nil, ErrUnknownMessage},
+
+		{"legacy move, not in a game", Legacy, "move to\n1\n2", false, nil, ErrNotInAGame},
+		{"json start, not in a game", JSON, `{"type":"start"}`, false, nil, ErrNotInAGame},
+		{"legacy join, in a game", Legacy, "join\nAB12\nfox", true, nil, ErrAlreadyInAGame},
+		{"json new game, in a game", JSON, `{"type":"newGame","data":{"name":"fox"}}`, true, nil, ErrAlreadyInAGame},
+
+		{"legacy unknown", Legacy, "dance\n1\n2", true, nil, ErrUnknownMessage},
+		{"json unknown", JSON, `{"type":"dance"}`, true, nil, ErrUnknownMessage},
+		{"legacy server message", Legacy, "go!", true, nil, ErrUnknownMessage},
+		{"json server message", JSON, `{"type":"go"}`, true, nil, ErrNotInbound},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.codec, []byte(test.frame), test.inGame)
+		switch {
+		case test.err == errAny && err == nil,
+			test.err != errAny && !errors.Is(err, test.err):
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		case test.err == nil && !reflect.DeepEqual(got, test.want):
+			t.Errorf("%s: got %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
+
+// one of every message, with its fields filled in
+var samples = map[string]interface{}{
+	"goodBye": GoodBye{},
+	"newGame": NewGame{Name: "fox", Rules: hideandseek.Ruleset{MoveRadius: 2, Seed: 1 << 60}, Emoji: "🦊", Map: "abc123", Aspect: 1.5},
+	"join": Join{Code: "AB12", Name: "fox", Emoji: "🦊", Aspect: 0.5},
+	"moveTo": MoveTo{Row: 1, Col: 2},
+	"ready": Ready{Stage: hideandseek.ReadyForNextSetup},
+	"start": Start{},
+	"addBot": AddBot{Difficulty: "hard"},
+	"spectate": Spectate{Code: "AB12", Name: "owl"},
+	"resume": Resume{Code: "AB12", Name: "fox", Token: "t0k"},
+	"replay": Replay{Seed: 1<<62 + 1},
+	"removeTree": RemoveTree{Row: 3, Col: 4},
+
+	"bye": Bye{},
+	"noSuchGame": NoSuchGame{Code: "AB13", DidYouMean: "AB12"},
+	"nameTaken": NameTaken{Name: "fox"},
+	"gameFull": GameFull{Code: "AB12"},
+	"tooManyGames": TooManyGames{},
+	"cantResume": CantResume{},
+	"error": Error{Reason: "no"},
+
+	"initialized": hideandseek.Initialized{Code: "AB12", Emoji: "🦊", Name: "fox", Token: "t0k"},
+	"wait": hideandseek.Wait{NextRound: true, Code: "AB12", Emoji: "🦊", Name: "fox", Token: "t0k", Others: []hideandseek.Avatar{{Emoji: "🐻", Name: "bear"}}},
+	"waitlisted": hideandseek.Waitlisted{Code: "AB12", Position: 2},
+	"spectating": hideandseek.Spectating{Code: "AB12", Players: []hideandseek.Avatar{{Emoji: "🐻", Name: "bear"}}},
+	"joined": hideandseek.Joined{Emoji: "🐻", Name: "bear"},
+	"left": hideandseek.Left{Emoji: "🐻", Name: "bear", Hiding: true, Row: 1, Col: 2},
+	"seekerLeft": hideandseek.SeekerLeft{},
+	"nowHost": hideandseek.NowHost{},
+	"tooFewHiders": hideandseek.TooFewHiders{},
+	"setup": hideandseek.Setup{
+		Seeker: "🦊",
+		Forest: hideandseek.Forest{{'🌲', hideandseek.OpenGround, hideandseek.River}, {hideandseek.Rock, '🌳', hideandseek.Thicket}},
+		Players: []hideandseek.Placement{{Emoji: "🦊", Name: "fox", Row: 0, Col: 0, Score: 3}, {Emoji: "🐻", Name: "bear", Row: 1, Col: 1}},
+		Rules: hideandseek.Ruleset{NoDiagonals: true, WinWhen: hideandseek.WinAllFound, Sight: 2},
+		Seed: 1<<63 - 1,
+	},
+	"go": hideandseek.Go{},
+	"moved": hideandseek.Moved{Emoji: "🦊", FromRow: 1, FromCol: 2, ToRow: 3, ToCol: 4},
+	"found": hideandseek.Found{Emoji: "🐻", Name: "bear", Row: 1, Col: 2},
+	"inSight": hideandseek.InSight{Players: []hideandseek.Placement{{Emoji: "🐻", Name: "bear", Row: 1, Col: 2}}},
+	"treeRemoved": hideandseek.TreeRemoved{Row: 1, Col: 2},
+	"winner": hideandseek.Winner{Emoji: "🐻", Name: "bear"},
+	"roundOver": hideandseek.RoundOver{Reason: hideandseek.ReasonTooFewHiders, NowSeeker: true, CantContinue: true},
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for name := range types {
+		m, ok := samples[name]
+		if !ok {
+			t.Errorf("%s: no sample (add one)", name)
+			continue
+		}
+		frames := JSON.Encode(m)
+		if len(frames) != 1 {
+			t.Errorf("%s: encoded to %d frames", name, len(frames))
+			continue
+		}
+		if !strings.HasPrefix(frames[0], `{"type":"`+name+`"`) {
+			t.Errorf("%s: encoded as %s", name, frames[0])
+		}
+		back, err := JSON.Decode([]byte(frames[0]))
+		if err != nil {
+			t.Errorf("%s: %s doesn't decode: %v", name, frames[0], err)
+			continue
+		}
+		if !reflect.DeepEqual(back, m) {
+			t.Errorf("%s: %s\n came back %#v\n      was %#v", name, frames[0], back, m)
+		}
+	}
+	for name := range samples {
+		if _, ok := types[name]; !ok {
+			t.Errorf("%s: a sample, but not a message", name)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package protocol is what goes over the websocket.
+//
+// There are two encodings. Legacy is the original one: newline separated
+// strings, first line says what the message is (see client.html). JSON is
+// {"type": ..., "data": {...}} using the structs below and the ones in
+// hideandseek; clients get it by asking for Subprotocol when they connect.
+package protocol
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/edmangimelli/hide-and-seek/hideandseek"
+)
+
+const Subprotocol = "hideandseek.v2.json"
+
+// client -> server
+
+type GoodBye struct{}
+
+type NewGame struct {
+	Name string `json:"name"`
+	Rules hideandseek.Ruleset `json:"rules"` // zero fields get the defaults
+	Emoji string `json:"emoji,omitempty"` // the avatar they'd like
+	Map string `json:"map,omitempty"` // the id of an uploaded map (POST /maps) to play on
+	Aspect float64 `json:"aspect,omitempty"` // their screen's height / width
+}
+
+type Join struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Emoji string `json:"emoji,omitempty"` // the avatar they'd like
+	Aspect float64 `json:"aspect,omitempty"` // their screen's height / width
+}
+
+type MoveTo struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+type Ready struct { // Stage is hideandseek.ReadyToGo or hideandseek.ReadyForNextSetup
+	Stage string `json:"stage"`
+}
+
+type Start struct{}
+
+type AddBot struct { // sit a server-side bot down in your game
+	Difficulty string `json:"difficulty"` // easy, medium (the default) or hard
+}
+
+type Spectate struct { // watch a game. Name is optional
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type Resume struct { // get your seat back after a dropped connection
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Token string `json:"token"`
+}
+
+type Replay struct { // host only: play a round again (see hideandseek.Replay)
+	Seed int64 `json:"seed,string,omitempty"` // a Setup's seed (a string, like it is there). 0: this round's
+}
+
+type RemoveTree struct { // trees are the server's business now; always rejected
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+// server -> client (on top of everything in hideandseek's messages.go)
+
+type Bye struct{}
+
+type NoSuchGame struct {
+	Code string `json:"code"`
+	DidYouMean string `json:"didYouMean,omitempty"` // a live code that's close, if it looks like a typo
+}
+
+type NameTaken struct {
+	Name string `json:"name"`
+}
+
+type GameFull struct { // and it has no waitlist
+	Code string `json:"code"`
+}
+
+type TooManyGames struct{}
+
+type CantResume struct{} // seat's gone (or the token's wrong); join again
+
+type Error struct {
+	Reason string `json:"reason"`
+}
+
+var (
+	ErrUnknownMessage = errors.New("unknown message")
+	ErrMissingFields = errors.New("message is missing fields")
+	ErrNotANumber = errors.New("row, col, aspect and seed must be numbers")
+)
+
+// A Codec turns messages into frames and back. A message is usually one
+// frame, but Legacy sends what old clients wouldn't expect in frames of its own.
+type Codec interface {
+	Encode(m interface{}) []string
+	Decode(frame []byte) (interface{}, error)
+}
+
+var (
+	Legacy Codec = legacy{}
+	JSON Codec = jsonCodec{}
+)
+
+// ForSubprotocol picks the codec for whatever the client negotiated.
+func ForSubprotocol(subprotocol string) Codec {
+	if subprotocol == Subprotocol {
+		return JSON
+	}
+	return Legacy
+}
+
+// types are the JSON names of every message, in either direction
+var types = map[string]interface{}{
+	"goodBye": GoodBye{},
+	"newGame": NewGame{},
+	"join": Join{},
+	"moveTo": MoveTo{},
+	"ready": Ready{},
+	"start": Start{},
+	"addBot": AddBot{},
+	"spectate": Spectate{},
+	"resume": Resume{},
+	"replay": Replay{},
+	"removeTree": RemoveTree{},
+
+	"bye": Bye{},
+	"noSuchGame": NoSuchGame{},
+	"nameTaken": NameTaken{},
+	"gameFull": GameFull{},
+	"tooManyGames": TooManyGames{},
+	"cantResume": CantResume{},
+	"error": Error{},
+
+	"initialized": hideandseek.Initialized{},
+	"wait": hideandseek.Wait{},
+	"waitlisted": hideandseek.Waitlisted{},
+	"spectating": hideandseek.Spectating{},
+	"joined": hideandseek.Joined{},
+	"left": hideandseek.Left{},
+	"seekerLeft": hideandseek.SeekerLeft{},
+	"nowHost": hideandseek.NowHost{},
+	"tooFewHiders": hideandseek.TooFewHiders{},
+	"setup": hideandseek.Setup{},
+	"go": hideandseek.Go{},
+	"moved": hideandseek.Moved{},
+	"found": hideandseek.Found{},
+	"inSight": hideandseek.InSight{},
+	"treeRemoved": hideandseek.TreeRemoved{},
+	"winner": hideandseek.Winner{},
+	"roundOver": hideandseek.RoundOver{},
+}
+
+var names = make(map[reflect.Type]string, len(types))
+
+func init() {
+	for name, v := range types {
+		names[reflect.TypeOf(v)] = name
+	}
+}
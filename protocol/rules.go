@@ -0,0 +1,96 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/edmangimelli/hide-and-seek/hideandseek"
+)
+
+var ErrBadRules = errors.New("bad rules")
+
+// In the legacy protocol a ruleset is one line of key=value pairs
+// (the keys are the JSON names), e.g. "treesPerPlayer=8 noDiagonals=true".
+// Keys that are left out get their defaults.
+
+func encodeRules(r hideandseek.Ruleset) string {
+	line := fmt.Sprintf("treesPerPlayer=%d aspect=%g moveRadius=%d noDiagonals=%t readyTimeout=%d maxPlayers=%d waitlist=%t winWhen=%s scoring=%s",
+		r.TreesPerPlayer, r.Aspect, r.MoveRadius, r.NoDiagonals, r.ReadyTimeout, r.MaxPlayers, r.Waitlist, r.WinWhen, r.Scoring)
+	if r.Theme != "" {
+		line += " theme=" + r.Theme
+	}
+	if r.Forest != "" {
+		line += " forest=" + r.Forest
+	}
+	if r.Terrain {
+		line += " terrain=true"
+	}
+	if r.Sight != 0 {
+		line += fmt.Sprintf(" sight=%d", r.Sight)
+	}
+	if r.Seed != 0 {
+		line += fmt.Sprintf(" seed=%d", r.Seed)
+	}
+	return line
+}
+
+func decodeRules(line string) (hideandseek.Ruleset, error) {
+	var r hideandseek.Ruleset
+	for _, pair := range strings.Fields(line) {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return r, ErrBadRules
+		}
+		key, value := kv[0], kv[1]
+
+		var err error
+		switch key {
+		case "treesPerPlayer":
+			r.TreesPerPlayer, err = strconv.Atoi(value)
+		case "aspect":
+			r.Aspect, err = parseFinite(value)
+		case "moveRadius":
+			r.MoveRadius, err = strconv.Atoi(value)
+		case "noDiagonals":
+			r.NoDiagonals, err = strconv.ParseBool(value)
+		case "readyTimeout":
+			r.ReadyTimeout, err = strconv.Atoi(value)
+		case "maxPlayers":
+			r.MaxPlayers, err = strconv.Atoi(value)
+		case "waitlist":
+			r.Waitlist, err = strconv.ParseBool(value)
+		case "winWhen":
+			r.WinWhen = value
+		case "scoring":
+			r.Scoring = value
+		case "theme":
+			r.Theme = value
+		case "forest":
+			r.Forest = value
+		case "terrain":
+			r.Terrain, err = strconv.ParseBool(value)
+		case "sight":
+			r.Sight, err = strconv.Atoi(value)
+		case "seed":
+			r.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return r, fmt.Errorf("%w: no such rule %q", ErrBadRules, key)
+		}
+		if err != nil {
+			return r, fmt.Errorf("%w: %s=%q", ErrBadRules, key, value)
+		}
+	}
+	return r, nil
+}
+
+// parseFinite is strconv.ParseFloat, minus NaN and the infinities
+func parseFinite(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err == nil && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return 0, strconv.ErrSyntax
+	}
+	return f, err
+}
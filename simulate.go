@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edmangimelli/hide-and-seek/bot"
+	"github.com/edmangimelli/hide-and-seek/hideandseek"
+)
+
+// simulate plays whole games between bots, in-process and on a made up
+// clock (each bot waits its Delay between moves, but nobody actually waits),
+// and prints how the rounds went. It's for tuning the forest and seeker rules:
+//
+//	hide-and-seek simulate -games 1000 -players 4 -seed 7 -format csv
+func simulate(args []string) {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	games := flags.Int("games", 100, "how many games to play")
+	players := flags.Int("players", 4, "bots per game")
+	rounds := flags.Int("rounds", 10, "rounds per game")
+	seed := flags.Int64("seed", 1, "game n is played with seed+n")
+	levels := flags.String("difficulty", "medium", "bot difficulty; a comma separated list is dealt out seat by seat")
+	limit := flags.Duration("limit", 10*time.Minute, "give up on a round after this long (simulated)")
+	format := flags.String("format", "csv", "csv or json")
+	report := flags.String("report", "rounds", "what csv reports: rounds or players")
+	ruleset := flags.String("rules", "{}", `the ruleset, as JSON (e.g. {"treesPerPlayer": 8})`)
+	flags.Parse(args)
+
+	var rules hideandseek.Ruleset
+	if err := json.Unmarshal([]byte(*ruleset), &rules); err != nil {
+		log.Fatalf("simulate: can't read rules: %s", err)
+	}
+	if err := rules.Validate(); err != nil {
+		log.Fatalf("simulate: %s", err)
+	}
+	if *players > rules.Filled().MaxPlayers {
+		log.Fatalf("simulate: the rules only allow %d players", rules.Filled().MaxPlayers)
+	}
+
+	if *players < 2 {
+		log.Fatalf("simulate: need at least 2 players")
+	}
+	var difficulties []bot.Difficulty
+	for _, l := range strings.Split(*levels, ",") {
+		d, err := bot.ParseDifficulty(strings.TrimSpace(l))
+		if err != nil {
+			log.Fatalf("simulate: %s", err)
+		}
+		difficulties = append(difficulties, d)
+	}
+
+	log.SetOutput(ioutil.Discard) // the engine logs every move
+	var results []simRound
+	for n := 0; n < *games; n++ {
+		results = append(results, simulateGame(n, *seed+int64(n), *players, *rounds, rules, difficulties, *limit)...)
+	}
+	log.SetOutput(os.Stderr)
+
+	summary := summarize(results, *players, difficulties)
+	switch *format {
+	case "json":
+		out := json.NewEncoder(os.Stdout)
+		out.SetIndent("", "  ")
+		out.Encode(summary)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		if *report == "players" {
+			w.Write([]string{"name", "difficulty", "rounds", "wins", "winRate", "timesSeeker", "seekerWins"})
+			for _, p := range summary.Players {
+				w.Write([]string{p.Name, p.Difficulty, strconv.Itoa(p.Rounds), strconv.Itoa(p.Wins),
+					ftoa(p.WinRate), strconv.Itoa(p.TimesSeeker), strconv.Itoa(p.SeekerWins)})
+			}
+		} else {
+			w.Write([]string{"game", "seed", "round", "players", "seeker", "winner", "seekerMoves", "hiderMoves", "seconds", "timedOut"})
+			for _, r := range results {
+				w.Write([]string{strconv.Itoa(r.Game), strconv.FormatInt(r.Seed, 10), strconv.Itoa(r.Round),
+					strconv.Itoa(r.Players), r.Seeker, r.Winner, strconv.Itoa(r.SeekerMoves),
+					ftoa(r.HiderMoves), ftoa(r.Seconds), strconv.FormatBool(r.TimedOut)})
+			}
+		}
+		w.Flush()
+	default:
+		log.Fatalf("simulate: unknown format %q (csv or json)", *format)
+	}
+}
+
+type simRound struct {
+	Game int `json:"game"`
+	Seed int64 `json:"seed"`
+	Round int `json:"round"`
+	Players int `json:"players"`
+	Seeker string `json:"seeker"`
+	Winner string `json:"winner"` // the last hider standing, or the seeker in a 2 player game
+	SeekerMoves int `json:"seekerMoves"`
+	HiderMoves float64 `json:"hiderMoves"` // average per hider
+	Seconds float64 `json:"seconds"` // from go to the end of the round
+	TimedOut bool `json:"timedOut"`
+}
+
+type simPlayer struct {
+	Name string `json:"name"`
+	Difficulty string `json:"difficulty"`
+	Rounds int `json:"rounds"`
+	Wins int `json:"wins"`
+	WinRate float64 `json:"winRate"`
+	TimesSeeker int `json:"timesSeeker"`
+	SeekerWins int `json:"seekerWins"`
+}
+
+type simSummary struct {
+	Games int `json:"games"`
+	Rounds int `json:"rounds"`
+	TimedOut int `json:"timedOut"`
+	SeekerMoves float64 `json:"seekerMoves"` // averages per round
+	HiderMoves float64 `json:"hiderMoves"`
+	Seconds float64 `json:"seconds"`
+	Players []simPlayer `json:"players"`
+	Results []simRound `json:"results"`
+}
+
+func simName(seat int) string {
+	return fmt.Sprintf("Bot %d", seat+1)
+}
+
+// simulateGame plays one game until it's had enough rounds, a round runs
+// past limit, or it can't go on.
+func simulateGame(n int, seed int64, players, rounds int, rules hideandseek.Ruleset, difficulties []bot.Difficulty, limit time.Duration) []simRound {
+	hideandseek.Seed(seed)
+	random := rand.New(rand.NewSource(seed))
+
+	g, _ := hideandseek.New("SIMULATION", rules)
+	bots := make(map[string]*bot.Bot)
+	next := make(map[string]time.Duration) // when each bot moves next
+	var seats []string
+	for i := 0; i < players; i++ {
+		b := bot.New(simName(i), difficulties[i%len(difficulties)], random.Int63())
+		bots[b.Name] = b
+		seats = append(seats, b.Name)
+	}
+
+	var now, began time.Duration
+	var results []simRound
+	var current *simRound // the round being played
+	var hiders []string
+	over := false
+
+	end := func(winner string, timedOut bool) {
+		if current == nil {
+			return
+		}
+		current.Winner = winner
+		current.TimedOut = timedOut
+		current.Seconds = (now - began).Seconds()
+		current.SeekerMoves = g.MovesThisRound(current.Seeker)
+		for _, h := range hiders {
+			current.HiderMoves += float64(g.MovesThisRound(h))
+		}
+		if len(hiders) > 0 {
+			current.HiderMoves /= float64(len(hiders))
+		}
+		results = append(results, *current)
+		current = nil
+		if timedOut || len(results) == rounds {
+			over = true
+		}
+	}
+
+	// run hands the game a command, and the bots everything the game says,
+	// until nobody has anything more to say
+	var run func(cmd hideandseek.Command)
+	run = func(cmd hideandseek.Command) {
+		events, _ := g.Handle(cmd) // a rejected move just means the bot stays put
+		var replies []hideandseek.Command
+		for _, e := range events {
+			b, exists := bots[e.To]
+			if !exists {
+				continue // timers: nobody here keeps anyone waiting
+			}
+			switch m := e.Message.(type) {
+			case hideandseek.Setup:
+				if current == nil {
+					current = &simRound{Game: n, Seed: seed, Round: len(results) + 1, Players: len(m.Players)}
+					hiders = nil
+					for _, p := range m.Players {
+						if p.Emoji == m.Seeker {
+							current.Seeker = p.Name
+						} else {
+							hiders = append(hiders, p.Name)
+						}
+					}
+				}
+				m.Forest = m.Forest.Copy() // each bot crosses trees off its own map
+				e.Message = m
+			case hideandseek.Go:
+				began = now
+			case hideandseek.Winner:
+				end(m.Name, false)
+			case hideandseek.RoundOver:
+				switch {
+				case m.Reason == hideandseek.ReasonTwoPlayerGame && current != nil:
+					end(current.Seeker, false)
+				case m.CantContinue:
+					over = true
+				}
+			}
+			replies = append(replies, b.Tell(e.Message)...)
+		}
+		for _, r := range replies {
+			if !over {
+				run(r)
+			}
+		}
+	}
+
+	for _, name := range seats {
+		run(hideandseek.Join{Name: name})
+	}
+	run(hideandseek.Start{Name: seats[0]})
+
+	for !over {
+		var mover string
+		for _, name := range seats { // whoever's up soonest
+			if mover == "" || next[name] < next[mover] {
+				mover = name
+			}
+		}
+		now = next[mover]
+		if current != nil && now-began > limit {
+			end("", true)
+			break
+		}
+		if cmd, ok := bots[mover].Move(); ok {
+			run(cmd)
+		}
+		next[mover] = now + bots[mover].Delay()
+	}
+	return results
+}
+
+func summarize(results []simRound, players int, difficulties []bot.Difficulty) simSummary {
+	s := simSummary{Results: results}
+	byName := make(map[string]*simPlayer)
+	for i := 0; i < players; i++ {
+		s.Players = append(s.Players, simPlayer{Name: simName(i), Difficulty: difficulties[i%len(difficulties)].String()})
+	}
+	for i := range s.Players {
+		byName[s.Players[i].Name] = &s.Players[i]
+	}
+
+	games := make(map[int]bool)
+	for _, r := range results {
+		games[r.Game] = true
+		if r.TimedOut {
+			s.TimedOut++
+			continue
+		}
+		s.Rounds++
+		s.SeekerMoves += float64(r.SeekerMoves)
+		s.HiderMoves += r.HiderMoves
+		s.Seconds += r.Seconds
+		for _, p := range s.Players {
+			byName[p.Name].Rounds++
+		}
+		byName[r.Seeker].TimesSeeker++
+		if w, exists := byName[r.Winner]; exists {
+			w.Wins++
+			if r.Winner == r.Seeker {
+				w.SeekerWins++
+			}
+		}
+	}
+	s.Games = len(games)
+
+	if s.Rounds > 0 {
+		s.SeekerMoves /= float64(s.Rounds)
+		s.HiderMoves /= float64(s.Rounds)
+		s.Seconds /= float64(s.Rounds)
+		for i := range s.Players {
+			s.Players[i].WinRate = float64(s.Players[i].Wins) / float64(s.Rounds)
+		}
+	}
+	return s
+}
+
+func ftoa(f float64) string {
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}